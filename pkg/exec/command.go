@@ -1,65 +1,164 @@
 package exec
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"time"
 )
 
 func ExternalCommand(command string) func(input *RunContext, args ...string) *RunContext {
 	return func(input *RunContext, args ...string) *RunContext {
+		return run(input, command, args, nil)
+	}
+}
 
-		// Create a context if it doesn't exist
-		if input.Ctx == nil {
-			ctx := context.Background()
-			input.Ctx = &ctx
-		}
+// run starts a command with the output of input as its stdin and env added to
+// its environment
+func run(input *RunContext, command string, args []string, env []string) *RunContext {
+	// A failed pipeline does not run any further commands
+	if input.Err != nil {
+		return input
+	}
 
-		cmd := exec.CommandContext(*input.Ctx, command, args...)
+	// Use the context of the program if there isn't one
+	if input.Ctx == nil {
+		ctx := Context()
+		input.Ctx = &ctx
+	}
 
-		// If there is input from a previous command, connect it to this command's stdin
-		if input != nil && input.Stdout != nil {
-			stdin, err := cmd.StdinPipe()
-			if err != nil {
-				return &RunContext{
-					Err: fmt.Errorf("failed to get stdin pipe: %v", err),
-				}
-			}
+	// An interrupted program does not start any more commands
+	interrupted(*input.Ctx)
 
-			go func() {
-				defer stdin.Close()
-				io.Copy(stdin, input.Stdout)
-			}()
-		}
+	cmd := exec.CommandContext(*input.Ctx, command, args...)
 
-		// Get stdout and stderr pipes
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	// Commands started in a sandbox block run with its limits
+	limits := limitsOf(*input.Ctx)
+	if limits != nil {
+		if err := limits.prepare(cmd); err != nil {
 			return &RunContext{
-				Err: fmt.Errorf("failed to get stdout pipe: %v", err),
+				Err: newStartError(command, args, err),
 			}
 		}
+	}
 
-		stderr, err := cmd.StderrPipe()
+	// If there is input from a previous command, connect it to this command's stdin
+	var copied chan struct{}
+	if input.Stdout != nil {
+		stdin, err := cmd.StdinPipe()
 		if err != nil {
 			return &RunContext{
-				Err: fmt.Errorf("failed to get stderr pipe: %v", err),
+				Err: fmt.Errorf("failed to get stdin pipe: %v", err),
 			}
 		}
 
-		// Start the command
-		if err := cmd.Start(); err != nil {
-			return &RunContext{
-				Err: fmt.Errorf("failed to start command: %v", err),
-			}
+		copied = make(chan struct{})
+		go func() {
+			defer close(copied)
+			defer stdin.Close()
+			io.Copy(stdin, input.Stdout)
+			// Unblock the previous command if this one exits early
+			input.Stdout.Close()
+		}()
+	}
+
+	// Get stdout pipe and collect stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return &RunContext{
+			Err: fmt.Errorf("failed to get stdout pipe: %v", err),
 		}
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	// The stderr of a pipeline is the stderr of all of its commands
+	var pipelineStderr io.Reader = &stderr
+	if input.Stderr != nil {
+		pipelineStderr = io.MultiReader(input.Stderr, &stderr)
+	}
 
-		// Return the RunContext with wait function
+	started := input.started
+	if started.IsZero() {
+		started = time.Now()
+	}
+
+	// Start the command
+	if err := cmd.Start(); err != nil {
 		return &RunContext{
-			Stdout: stdout,
-			Stderr: stderr,
-			Ctx:    input.Ctx,
+			Err: newStartError(command, args, err),
+			Ctx: input.Ctx,
+		}
+	}
+
+	// Return the RunContext with wait function
+	return &RunContext{
+		Stdout:  stdout,
+		Stderr:  io.NopCloser(pipelineStderr),
+		Ctx:     input.Ctx,
+		started: started,
+		wait: func() error {
+			if copied != nil {
+				<-copied
+			}
+
+			var prevErr error
+			if input.wait != nil {
+				prevErr = input.wait()
+			}
+
+			if err := cmd.Wait(); err != nil {
+				// The command was killed because the program was interrupted
+				if cause := context.Cause(*input.Ctx); errors.Is(cause, ErrInterrupted) {
+					return cause
+				}
+
+				cmdErr := newCommandError(command, args, stderr.String(), err)
+
+				if limits != nil {
+					if resource, limit := limits.violation(cmd.ProcessState, stderr.String()); resource != "" {
+						return &LimitError{CommandError: cmdErr, Resource: resource, Limit: limit}
+					}
+				}
+
+				return cmdErr
+			}
+
+			// Commands that stop reading early leave the previous command
+			// with a broken pipe, which is not a failure of the pipeline
+			if prevErr != nil && !isBrokenPipe(prevErr) {
+				return prevErr
+			}
+
+			return nil
+		},
+	}
+}
+
+// Args flattens the arguments of a command, lists are passed as one argument
+// per item: rm(-f, files) becomes rm -f a b
+func Args(args ...any) []string {
+	flat := []string{}
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case string:
+			flat = append(flat, arg)
+		case []string:
+			flat = append(flat, arg...)
+		case []any:
+			flat = append(flat, Args(arg...)...)
+		default:
+			flat = append(flat, fmt.Sprint(arg))
 		}
 	}
+	return flat
 }
@@ -0,0 +1,77 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ErrInterrupted is the error of a program that received an interrupt or a
+// termination signal, see Context
+var ErrInterrupted = errors.New("interrupted")
+
+// program is the context of the program, it's cancelled when the program
+// receives an interrupt or a termination signal. Commands started with it are
+// killed, the program stops at the next command or sleep, and a second signal
+// terminates it right away.
+var program = sync.OnceValue(func() context.Context {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		cancel(ErrInterrupted)
+		signal.Stop(signals)
+	}()
+
+	return ctx
+})
+
+// interrupted raises ErrInterrupted if ctx was cancelled by a signal, it's an
+// unhandled error that ends the program, see std.HandleExit
+func interrupted(ctx context.Context) {
+	if err := context.Cause(ctx); errors.Is(err, ErrInterrupted) {
+		panic(err)
+	}
+}
+
+// sandbox is the context of the innermost sandbox block that is running
+var sandbox context.Context
+
+// Context returns the context that commands start with, the context of the
+// program or of the sandbox block that is running, see Sandbox
+func Context() context.Context {
+	if sandbox != nil {
+		return sandbox
+	}
+
+	return program()
+}
+
+// limitsKey holds the Limits of a context
+type limitsKey struct{}
+
+// limitsOf returns the limits of the commands started with ctx, nil if there
+// are none
+func limitsOf(ctx context.Context) *Limits {
+	limits, _ := ctx.Value(limitsKey{}).(*Limits)
+	return limits
+}
+
+// Sandbox runs block with limits for the commands it starts, including those
+// of the functions it calls. In nested sandboxes each limit is the lowest of
+// all of them.
+func Sandbox(limits *Limits, block func()) {
+	outer := sandbox
+	defer func() { sandbox = outer }()
+
+	ctx := Context()
+	sandbox = context.WithValue(ctx, limitsKey{}, limitsOf(ctx).merge(limits))
+
+	block()
+}
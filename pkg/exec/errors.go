@@ -0,0 +1,100 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// CommandError is the error of an external command that failed to start or
+// exited with a non-zero status
+type CommandError struct {
+	Command string
+	Args    []string
+	Code    int
+	Stderr  string
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("command %s failed with exit code %d: %v", e.Command, e.Code, e.Err)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += "\n" + stderr
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+func newCommandError(command string, args []string, stderr string, err error) *CommandError {
+	cmdErr := &CommandError{
+		Command: command,
+		Args:    args,
+		Code:    1,
+		Stderr:  stderr,
+		Err:     err,
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// Follow the shell convention for commands killed by a signal
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			cmdErr.Code = 128 + int(status.Signal())
+		} else {
+			cmdErr.Code = exitErr.ExitCode()
+		}
+	}
+
+	return cmdErr
+}
+
+func newStartError(command string, args []string, err error) *CommandError {
+	cmdErr := newCommandError(command, args, "", err)
+
+	// Follow the shell convention for commands that could not be executed
+	if errors.Is(err, exec.ErrNotFound) {
+		cmdErr.Code = 127
+	} else {
+		cmdErr.Code = 126
+	}
+
+	return cmdErr
+}
+
+// ExitCode returns the process exit status that corresponds to an error
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	// A failed retry exits like its last attempt
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) && len(retryErr.Errors) > 0 {
+		return ExitCode(retryErr.Errors[len(retryErr.Errors)-1])
+	}
+
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code
+	}
+
+	// The program was interrupted, see Context
+	if errors.Is(err, ErrInterrupted) {
+		return 130
+	}
+
+	return 1
+}
+
+func isBrokenPipe(err error) bool {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status, ok := exitErr.Sys().(syscall.WaitStatus)
+		return ok && status.Signaled() && status.Signal() == syscall.SIGPIPE
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	cmdErr := &CommandError{Command: "ls", Code: 2, Err: errors.New("exit status 2")}
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"no error", nil, 0},
+		{"command", cmdErr, 2},
+		{"wrapped command", fmt.Errorf("deploy: %w", cmdErr), 2},
+		{"retry", &RetryError{Errors: []error{errors.New("failed"), cmdErr}}, 2},
+		{"interrupt", ErrInterrupted, 130},
+		{"interrupted retry", &RetryError{Errors: []error{cmdErr, ErrInterrupted}}, 130},
+		{"other", errors.New("failed"), 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if code := ExitCode(test.err); code != test.code {
+				t.Fatalf("expected %d, got %d", test.code, code)
+			}
+		})
+	}
+}
+
+func TestCommandExitCodes(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		code    int
+	}{
+		{"exit status", "sh", []string{"-c", "exit 3"}, 3},
+		{"signal", "sh", []string{"-c", "kill -TERM $$"}, 143},
+		{"not found", "posh-no-such-command", nil, 127},
+		{"not executable", script, nil, 126},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := ExternalCommand(test.command)(&RunContext{}, test.args...).Wait()
+			if code := ExitCode(res.Err); code != test.code {
+				t.Fatalf("expected %d, got %d (%v)", test.code, code, res.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package exec
+
+import "fmt"
+
+// Limits restricts the resources of the commands started in a sandbox block,
+// see Sandbox. Zero values mean no limit.
+type Limits struct {
+	// CPU is the maximum CPU time of a command in seconds
+	CPU uint64
+	// Memory is the maximum size of the address space of a command in bytes
+	Memory uint64
+	// Files is the maximum number of files a command can open
+	Files uint64
+	// Procs is the maximum number of processes of the user running a command
+	Procs uint64
+	// NewGroup runs commands in a new process group, so they don't receive
+	// the signals sent to the group of the PoSH program
+	NewGroup bool
+	// Uid is the user commands run as, nil keeps the current user
+	Uid *uint32
+}
+
+// User returns the uid to use in Limits
+func User(uid int) *uint32 {
+	value := uint32(uid)
+	return &value
+}
+
+// merge returns the limits of a sandbox inside of one with l: the lowest of
+// each limit, a new group if either asks for one and the user of the outer
+// one if it sets one
+func (l *Limits) merge(inner *Limits) *Limits {
+	if l == nil {
+		return inner
+	}
+
+	merged := *l
+	merged.CPU = lowest(l.CPU, inner.CPU)
+	merged.Memory = lowest(l.Memory, inner.Memory)
+	merged.Files = lowest(l.Files, inner.Files)
+	merged.Procs = lowest(l.Procs, inner.Procs)
+	merged.NewGroup = l.NewGroup || inner.NewGroup
+
+	if merged.Uid == nil {
+		merged.Uid = inner.Uid
+	}
+
+	return &merged
+}
+
+// lowest returns the lowest of two limits, zero is no limit
+func lowest(a, b uint64) uint64 {
+	if a == 0 || b != 0 && b < a {
+		return b
+	}
+
+	return a
+}
+
+func (l *Limits) isEmpty() bool {
+	return l.CPU == 0 && l.Memory == 0 && l.Files == 0 && l.Procs == 0 && !l.NewGroup && l.Uid == nil
+}
+
+// LimitError is the error of a command that exceeded one of its Limits
+type LimitError struct {
+	*CommandError
+	Resource string
+	Limit    uint64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("command %s exceeded its %s limit of %d", e.Command, e.Resource, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.CommandError
+}
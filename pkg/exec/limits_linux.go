@@ -0,0 +1,223 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// RLIMIT_NPROC is not exported by the syscall package
+const rlimitNproc = 0x6
+
+// prepare sets up the process attributes of a command before it starts
+func (l *Limits) prepare(cmd *exec.Cmd) error {
+	attr := &syscall.SysProcAttr{}
+
+	if l.NewGroup {
+		attr.Setpgid = true
+
+		// Cancelling the context should stop the whole group
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+	}
+
+	if l.Uid != nil {
+		gid := uint32(os.Getgid())
+		if u, err := user.LookupId(strconv.Itoa(int(*l.Uid))); err == nil {
+			if value, err := strconv.Atoi(u.Gid); err == nil {
+				gid = uint32(value)
+			}
+		}
+
+		attr.Credential = &syscall.Credential{Uid: *l.Uid, Gid: gid}
+	}
+
+	cmd.SysProcAttr = attr
+	l.wrap(cmd)
+	return nil
+}
+
+// sandboxArg is the first argument of a command that the program runs through
+// itself, it's followed by the limits and the path of the command, see
+// trampoline
+const sandboxArg = "posh-sandbox"
+
+// Limits can't be set between fork and exec from Go, and setting them once the
+// command has started lets it open files or allocate memory before they apply.
+// Instead the program starts itself with the limits in its arguments, sets
+// them on its own process and then execs the command, which keeps them. The
+// arguments are replaced by exec, so unlike the environment they don't reach
+// the command or the commands it starts.
+func init() {
+	if len(os.Args) > 3 && os.Args[0] == sandboxArg {
+		trampoline(os.Args[1], os.Args[2], os.Args[3:])
+	}
+}
+
+// wrap makes a command start through the program itself, which sets the limits
+// before it execs the command
+func (l *Limits) wrap(cmd *exec.Cmd) {
+	// The command was not found, Start reports it
+	if cmd.Err != nil {
+		return
+	}
+
+	if l.CPU == 0 && l.Memory == 0 && l.Files == 0 && l.Procs == 0 {
+		return
+	}
+
+	spec := fmt.Sprintf("%d,%d,%d,%d", l.CPU, l.Memory, l.Files, l.Procs)
+	cmd.Args = append([]string{sandboxArg, spec, cmd.Path}, cmd.Args...)
+	cmd.Path = "/proc/self/exe"
+}
+
+// trampoline sets the limits in spec on the current process and execs the
+// command at path with args, it only returns if that fails
+func trampoline(spec string, path string, args []string) {
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "posh: failed to start command in sandbox: %v\n", err)
+		os.Exit(126)
+	}
+
+	parts := strings.Split(spec, ",")
+	if len(parts) != 4 {
+		fail(fmt.Errorf("invalid limits %q", spec))
+	}
+
+	values := make([]uint64, 4)
+	for i := range values {
+		value, err := strconv.ParseUint(parts[i], 10, 64)
+		if err != nil {
+			fail(fmt.Errorf("invalid limits %q", spec))
+		}
+		values[i] = value
+	}
+
+	limits := &Limits{CPU: values[0], Memory: values[1], Files: values[2], Procs: values[3]}
+
+	// Everything exec needs is allocated before the limits are set, a memory
+	// limit can leave no room for the Go runtime to grow
+	pathp, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		fail(err)
+	}
+	argv, err := syscall.SlicePtrFromStrings(args)
+	if err != nil {
+		fail(err)
+	}
+	envv, err := syscall.SlicePtrFromStrings(os.Environ())
+	if err != nil {
+		fail(err)
+	}
+
+	if err := limits.apply(); err != nil {
+		fail(err)
+	}
+
+	_, _, errno := syscall.RawSyscall(
+		syscall.SYS_EXECVE,
+		uintptr(unsafe.Pointer(pathp)),
+		uintptr(unsafe.Pointer(&argv[0])),
+		uintptr(unsafe.Pointer(&envv[0])),
+	)
+
+	fail(errno)
+}
+
+// apply sets the resource limits of the current process, they are kept by the
+// commands it execs
+func (l *Limits) apply() error {
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{syscall.RLIMIT_CPU, l.CPU},
+		{syscall.RLIMIT_AS, l.Memory},
+		{syscall.RLIMIT_NOFILE, l.Files},
+		{rlimitNproc, l.Procs},
+	}
+
+	for _, limit := range limits {
+		if limit.value == 0 {
+			continue
+		}
+
+		rlimit := syscall.Rlimit{Cur: limit.value, Max: limit.value}
+
+		// The kernel sends SIGXCPU at the soft CPU limit, which only stops
+		// the command if the hard one is higher, see violation
+		if limit.resource == syscall.RLIMIT_CPU {
+			rlimit.Max++
+		}
+		_, _, errno := syscall.RawSyscall6(
+			syscall.SYS_PRLIMIT64,
+			0,
+			uintptr(limit.resource),
+			uintptr(unsafe.Pointer(&rlimit)),
+			0, 0, 0,
+		)
+
+		if errno != 0 {
+			return fmt.Errorf("failed to set resource limit: %v", errno)
+		}
+	}
+
+	return nil
+}
+
+// limitMessages are what commands print when a call fails because of a limit:
+// the strerror of the errno the kernel returns and the messages of common
+// shells and runtimes. The errno numbers and messages such as "Resource
+// temporarily unavailable" are left out, commands print them for failures
+// that have nothing to do with the limits.
+var limitMessages = []struct {
+	resource string
+	messages []string
+	// unrelated contain one of the messages but are not caused by the limit
+	unrelated []string
+}{
+	{"memory", []string{"Cannot allocate memory", "out of memory", "memory exhausted", "MemoryError", "std::bad_alloc"}, nil},
+	{"files", []string{"Too many open files"}, []string{"Too many open files in system"}},
+	{"procs", []string{"Cannot fork", "fork: retry", "fork: Resource temporarily unavailable"}, nil},
+}
+
+// violation returns the limit a failed command exceeded, if it can be told
+// from how the command exited or from what it printed to stderr
+func (l *Limits) violation(state *os.ProcessState, stderr string) (string, uint64) {
+	// The kernel sends SIGXCPU at the soft CPU limit and SIGKILL at the hard
+	// one, a second later
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() && l.CPU != 0 {
+		cpuTime := state.UserTime() + state.SystemTime()
+		if status.Signal() == syscall.SIGXCPU || (status.Signal() == syscall.SIGKILL && cpuTime >= time.Duration(l.CPU)*time.Second) {
+			return "cpu", l.CPU
+		}
+	}
+
+	// The other limits fail system calls, commands tell that they failed
+	values := map[string]uint64{"memory": l.Memory, "files": l.Files, "procs": l.Procs}
+	for _, limit := range limitMessages {
+		if values[limit.resource] == 0 {
+			continue
+		}
+
+		output := stderr
+		for _, message := range limit.unrelated {
+			output = strings.ReplaceAll(output, message, "")
+		}
+
+		for _, message := range limit.messages {
+			if strings.Contains(output, message) {
+				return limit.resource, values[limit.resource]
+			}
+		}
+	}
+
+	return "", 0
+}
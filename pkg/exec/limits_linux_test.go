@@ -0,0 +1,116 @@
+package exec
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func runLimited(t *testing.T, limits *Limits, script string) *RunContext {
+	t.Helper()
+
+	var res *RunContext
+	Sandbox(limits, func() {
+		res = ExternalCommand("sh")(&RunContext{}, "-c", script).Wait()
+	})
+
+	return res
+}
+
+func TestLimitsApplyBeforeExec(t *testing.T) {
+	// With three files the dynamic loader of the shell can't open libc, which
+	// only happens when the limit is set before the shell starts
+	for i := 0; i < 5; i++ {
+		res := runLimited(t, &Limits{Files: 3}, "true")
+
+		var cmdErr *CommandError
+		if !errors.As(res.Err, &cmdErr) || !strings.Contains(cmdErr.Stderr, "error while loading shared libraries") {
+			t.Fatalf("expected the shell to fail to load, got %v", res.Err)
+		}
+	}
+}
+
+func TestLimitsOfCommands(t *testing.T) {
+	res := runLimited(t, &Limits{CPU: 5, Files: 64}, "ulimit -t; ulimit -n")
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	if output := res.ToString(); output != "5\n64\n" {
+		t.Fatalf("unexpected output %q", output)
+	}
+}
+
+func TestLimitsKeepArguments(t *testing.T) {
+	// the command gets its own arguments and nothing is added to its
+	// environment
+	res := runLimited(t, &Limits{Files: 64}, `echo "$0 $#"; env | grep -c posh-sandbox || true`)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	if output := res.ToString(); output != "sh 0\n0\n" {
+		t.Fatalf("unexpected output %q", output)
+	}
+}
+
+func TestNestedSandboxes(t *testing.T) {
+	var res *RunContext
+	Sandbox(&Limits{Files: 64}, func() {
+		res = runLimited(t, &Limits{CPU: 5, Files: 128}, "ulimit -t; ulimit -n")
+	})
+
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	if output := res.ToString(); output != "5\n64\n" {
+		t.Fatalf("unexpected output %q", output)
+	}
+
+	if limits := limitsOf(Context()); limits != nil {
+		t.Fatalf("expected no limits outside of the sandboxes, got %+v", limits)
+	}
+}
+
+func TestCPULimit(t *testing.T) {
+	res := runLimited(t, &Limits{CPU: 1}, "while :; do :; done")
+
+	var limitErr *LimitError
+	if !errors.As(res.Err, &limitErr) || limitErr.Resource != "cpu" {
+		t.Fatalf("expected the cpu limit error, got %v", res.Err)
+	}
+}
+
+func TestLimitViolations(t *testing.T) {
+	// A command that exits with a failure, like one stopped by a limit
+	cmd := exec.Command("sh", "-c", "exit 1")
+	cmd.Run()
+
+	tests := []struct {
+		name     string
+		limits   *Limits
+		stderr   string
+		resource string
+	}{
+		{"files", &Limits{Files: 16}, "cat: /etc/hosts: Too many open files", "files"},
+		{"files of the system", &Limits{Files: 16}, "cat: /etc/hosts: Too many open files in system", ""},
+		{"loader", &Limits{Files: 3}, "error while loading shared libraries: libc.so.6: cannot open shared object file: Error 24", ""},
+		{"memory", &Limits{Memory: 1 << 26}, "fatal error: runtime: out of memory", "memory"},
+		{"procs", &Limits{Procs: 1}, "sh: 1: Cannot fork", "procs"},
+		{"procs of bash", &Limits{Procs: 1}, "bash: fork: retry: Resource temporarily unavailable", "procs"},
+		{"unavailable", &Limits{Procs: 1}, "curl: (6) Resource temporarily unavailable", ""},
+		{"no limit", &Limits{Files: 16}, "fatal error: runtime: out of memory", ""},
+		{"other failure", &Limits{Memory: 1 << 26, Files: 16, Procs: 1}, "cat: x: No such file or directory", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resource, _ := test.limits.violation(cmd.ProcessState, test.stderr)
+			if resource != test.resource {
+				t.Fatalf("expected %q, got %q", test.resource, resource)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package exec
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func (l *Limits) prepare(cmd *exec.Cmd) error {
+	if !l.isEmpty() {
+		return fmt.Errorf("resource limits are only supported on linux")
+	}
+
+	return nil
+}
+
+func (l *Limits) violation(state *os.ProcessState, stderr string) (string, uint64) {
+	return "", 0
+}
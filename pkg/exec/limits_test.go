@@ -0,0 +1,61 @@
+package exec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		outer  *Limits
+		inner  *Limits
+		merged *Limits
+	}{
+		{"no outer limits", nil, &Limits{CPU: 5}, &Limits{CPU: 5}},
+		{"lowest", &Limits{CPU: 5, Memory: 1 << 30}, &Limits{CPU: 10, Memory: 1 << 20}, &Limits{CPU: 5, Memory: 1 << 20}},
+		{"zero is no limit", &Limits{Files: 64}, &Limits{Procs: 8}, &Limits{Files: 64, Procs: 8}},
+		{"group", &Limits{NewGroup: true}, &Limits{}, &Limits{NewGroup: true}},
+		{"outer user", &Limits{Uid: User(1000)}, &Limits{Uid: User(0)}, &Limits{Uid: User(1000)}},
+		{"inner user", &Limits{}, &Limits{Uid: User(1000)}, &Limits{Uid: User(1000)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if merged := test.outer.merge(test.inner); !reflect.DeepEqual(merged, test.merged) {
+				t.Fatalf("expected %+v, got %+v", test.merged, merged)
+			}
+		})
+	}
+}
+
+func TestSandboxContext(t *testing.T) {
+	calls := 0
+	check := func(expected *Limits) {
+		calls++
+		if limits := limitsOf(Context()); !reflect.DeepEqual(limits, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, limits)
+		}
+	}
+
+	// functions called in a sandbox get its limits
+	Sandbox(&Limits{Files: 64}, func() {
+		check(&Limits{Files: 64})
+		Sandbox(&Limits{Files: 128, CPU: 5}, func() {
+			check(&Limits{Files: 64, CPU: 5})
+		})
+		check(&Limits{Files: 64})
+	})
+	check(nil)
+
+	// a failure in a sandbox leaves it
+	func() {
+		defer func() { recover() }()
+		Sandbox(&Limits{Files: 64}, func() { panic("failed") })
+	}()
+	check(nil)
+
+	if calls != 5 {
+		t.Fatalf("expected 5 checks, got %d", calls)
+	}
+}
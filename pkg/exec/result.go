@@ -0,0 +1,50 @@
+package exec
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of a pipeline whose failure is handled by the script
+// instead of terminating the program
+type Result struct {
+	Stdout   string
+	Stderr   string
+	Code     int
+	Ok       bool
+	Duration time.Duration
+	Lines    []string
+}
+
+// Complete waits for a pipeline and returns its outcome as a Result
+func Complete(r *RunContext) *Result {
+	r.Wait()
+
+	result := &Result{
+		Code:     ExitCode(r.Err),
+		Ok:       r.Err == nil,
+		Duration: time.Since(r.started),
+		Lines:    []string{},
+	}
+
+	if r.started.IsZero() {
+		result.Duration = 0
+	}
+
+	if r.Stdout != nil {
+		data, _ := io.ReadAll(r.Stdout)
+		result.Stdout = string(data)
+	}
+
+	if r.Stderr != nil {
+		data, _ := io.ReadAll(r.Stderr)
+		result.Stderr = string(data)
+	}
+
+	if output := strings.TrimRight(result.Stdout, "\n"); output != "" {
+		result.Lines = strings.Split(output, "\n")
+	}
+
+	return result
+}
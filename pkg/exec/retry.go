@@ -0,0 +1,110 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type Backoff int
+
+const (
+	Constant Backoff = iota
+	Linear
+	Exponential
+)
+
+type RetryOptions struct {
+	// Times is the maximum number of attempts
+	Times int
+	// Backoff is how the delay grows between attempts
+	Backoff Backoff
+	// Delay is the wait before the second attempt
+	Delay time.Duration
+}
+
+func (o RetryOptions) delay(attempt int) time.Duration {
+	switch o.Backoff {
+	case Linear:
+		return o.Delay * time.Duration(attempt)
+	case Exponential:
+		return o.Delay << (attempt - 1)
+	default:
+		return o.Delay
+	}
+}
+
+// RetryError is the error of a block that failed on every attempt
+type RetryError struct {
+	Errors []error
+}
+
+func (e *RetryError) Error() string {
+	lines := []string{fmt.Sprintf("failed after %d attempts:", len(e.Errors))}
+	for i, err := range e.Errors {
+		lines = append(lines, fmt.Sprintf("attempt %d: %v", i+1, err))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *RetryError) Unwrap() []error {
+	return e.Errors
+}
+
+// Seconds converts a number of seconds to a time.Duration
+func Seconds[T int | float64](seconds T) time.Duration {
+	return time.Duration(float64(seconds) * float64(time.Second))
+}
+
+// Retry runs block until it doesn't fail, at most options.Times times, and
+// waits between the attempts. It stops early when ctx is cancelled. If every
+// attempt fails the errors of all attempts are raised as a RetryError.
+func Retry(ctx context.Context, options RetryOptions, block func()) {
+	retryErr := &RetryError{}
+
+	for attempt := 1; attempt <= options.Times; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(options.delay(attempt - 1))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				retryErr.Errors = append(retryErr.Errors, context.Cause(ctx))
+				panic(retryErr)
+			case <-timer.C:
+			}
+		}
+
+		err := try(block)
+		if err == nil {
+			return
+		}
+
+		retryErr.Errors = append(retryErr.Errors, err)
+	}
+
+	panic(retryErr)
+}
+
+// try runs block and recovers the unhandled error it raises, if any
+func try(block func()) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		// Interrupts are not retried, they end the program
+		var ok bool
+		err, ok = recovered.(error)
+		if _, isRuntime := recovered.(runtime.Error); !ok || isRuntime || errors.Is(err, ErrInterrupted) {
+			panic(recovered)
+		}
+	}()
+
+	block()
+	return nil
+}
@@ -0,0 +1,107 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		backoff Backoff
+		delays  []time.Duration
+	}{
+		{Constant, []time.Duration{2, 2, 2, 2}},
+		{Linear, []time.Duration{2, 4, 6, 8}},
+		{Exponential, []time.Duration{2, 4, 8, 16}},
+	}
+
+	for _, test := range tests {
+		options := RetryOptions{Backoff: test.backoff, Delay: 2}
+		for i, delay := range test.delays {
+			if got := options.delay(i + 1); got != delay {
+				t.Errorf("backoff %d: expected a delay of %d after attempt %d, got %d", test.backoff, delay, i+1, got)
+			}
+		}
+	}
+}
+
+func TestRetrySucceeds(t *testing.T) {
+	attempts := 0
+	Retry(context.Background(), RetryOptions{Times: 5, Backoff: Exponential}, func() {
+		attempts++
+		if attempts < 3 {
+			panic(fmt.Errorf("attempt %d", attempts))
+		}
+	})
+
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryFails(t *testing.T) {
+	attempts := 0
+	err := try(func() {
+		Retry(context.Background(), RetryOptions{Times: 3}, func() {
+			attempts++
+			panic(fmt.Errorf("attempt %d", attempts))
+		})
+	})
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected a RetryError, got %v", err)
+	}
+
+	if attempts != 3 || len(retryErr.Errors) != 3 {
+		t.Fatalf("expected 3 attempts and errors, got %d and %d", attempts, len(retryErr.Errors))
+	}
+
+	expected := "failed after 3 attempts:\nattempt 1: attempt 1\nattempt 2: attempt 2\nattempt 3: attempt 3"
+	if err.Error() != expected {
+		t.Fatalf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	attempts := 0
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrInterrupted) || ExitCode(err) != 130 {
+			t.Fatalf("expected an interrupt, got %v", err)
+		}
+
+		if attempts != 1 {
+			t.Fatalf("expected 1 attempt, got %d", attempts)
+		}
+	}()
+
+	Retry(ctx, RetryOptions{Times: 3, Delay: time.Hour}, func() {
+		attempts++
+		cancel(ErrInterrupted)
+		panic(errors.New("failed"))
+	})
+}
+
+func TestRetryDoesNotRetryInterrupts(t *testing.T) {
+	attempts := 0
+	defer func() {
+		if recovered := recover(); recovered != ErrInterrupted {
+			t.Fatalf("expected the interrupt to be raised, got %v", recovered)
+		}
+
+		if attempts != 1 {
+			t.Fatalf("expected 1 attempt, got %d", attempts)
+		}
+	}()
+
+	Retry(context.Background(), RetryOptions{Times: 3}, func() {
+		attempts++
+		panic(ErrInterrupted)
+	})
+}
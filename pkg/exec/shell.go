@@ -0,0 +1,20 @@
+package exec
+
+import "fmt"
+
+// Shell returns a command that runs a script snippet with the given shell,
+// env is passed to the script as environment variables, see Env
+func Shell(shell string) func(input *RunContext, script string, env ...string) *RunContext {
+	return func(input *RunContext, script string, env ...string) *RunContext {
+		return run(input, shell, []string{"-c", script}, env)
+	}
+}
+
+var Sh = Shell("/bin/sh")
+var Bash = Shell("bash")
+
+// Env formats a variable for the environment of a shell script, passing
+// values this way keeps them from being interpreted by the shell
+func Env(name string, value any) string {
+	return fmt.Sprintf("%s=%v", name, value)
+}
@@ -1,9 +1,14 @@
 package exec
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
-	"sync"
+	"os"
+	"strings"
+	"time"
 )
 
 type RunContext struct {
@@ -11,23 +16,60 @@ type RunContext struct {
 	Stderr io.ReadCloser
 	Ctx    *context.Context
 	Err    error
+
+	// started is when the first command of the pipeline started
+	started time.Time
+	// wait blocks until the command that produced Stdout, and every command
+	// before it in the pipeline, has exited
+	wait   func() error
+	waited bool
 }
 
+// Wait drains the output of the pipeline, waits for all of its commands to
+// exit and records the failure of the pipeline, if any, in Err
 func (r *RunContext) Wait() *RunContext {
-	if r.Ctx != nil {
-		var wg sync.WaitGroup
-		wg.Add(1)
+	if r.waited {
+		return r
+	}
+
+	r.waited = true
+
+	if r.wait == nil {
+		return r
+	}
+
+	// The output must be fully read before the commands can be waited on
+	var stdout bytes.Buffer
+	if r.Stdout != nil {
+		io.Copy(&stdout, r.Stdout)
+	}
+	r.Stdout = io.NopCloser(&stdout)
 
-		go func() {
-			defer wg.Done()
-			<-(*r.Ctx).Done()
-		}()
+	if err := r.wait(); err != nil && r.Err == nil {
+		r.Err = err
+	}
+
+	// An interrupt ends the program, also in pipes that end with complete()
+	if errors.Is(r.Err, ErrInterrupted) {
+		panic(r.Err)
 	}
 
 	return r
 }
 
+// ToString returns the output of the pipeline. A failed pipeline is an
+// unhandled error and is raised as a panic, see std.HandleExit
 func (r *RunContext) ToString() string {
+	r.Wait()
+
+	if r.Err != nil {
+		panic(r.Err)
+	}
+
+	if r.Stdout == nil {
+		return ""
+	}
+
 	data, err := io.ReadAll(r.Stdout)
 	if err != nil {
 		return ""
@@ -35,6 +77,29 @@ func (r *RunContext) ToString() string {
 	return string(data)
 }
 
+// Run streams the output of a pipeline to the output of the program and
+// waits for it. A failed pipeline is an unhandled error, see ToString.
+func Run(r *RunContext) {
+	if r.Stdout != nil {
+		io.Copy(os.Stdout, r.Stdout)
+	}
+
+	r.Wait()
+
+	if r.Err != nil {
+		panic(r.Err)
+	}
+
+	if r.Stderr != nil {
+		io.Copy(os.Stderr, r.Stderr)
+	}
+}
+
+// Input returns a reader to use a value as the stdin of a pipeline
+func Input(value any) io.ReadCloser {
+	return io.NopCloser(strings.NewReader(fmt.Sprint(value)))
+}
+
 func NewContext() *RunContext {
 	return &RunContext{}
 }
@@ -0,0 +1,26 @@
+package json
+
+import (
+	"encoding/json"
+)
+
+// Encode returns the JSON encoding of a value, the fields of records are
+// encoded with their PoSH names
+func Encode(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(data)
+}
+
+// Decode parses JSON into a value of the same type as into:
+// json.Decode(text, Host{})
+func Decode[T any](data string, into T) T {
+	if err := json.Unmarshal([]byte(data), &into); err != nil {
+		panic(err)
+	}
+
+	return into
+}
@@ -0,0 +1,68 @@
+package lexer
+
+import (
+	"strings"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// heredocEscapes are the only escape sequences of heredocs, other backslashes
+// are kept as they are, like in shell heredocs
+var heredocEscapes = strings.NewReplacer(`\\`, `\`, `\$`, `$`)
+
+// scanHeredoc scans a heredoc, which lexes as a HEREDOC token followed by its
+// body as a string. The body starts on the next line and ends at the line that
+// is the marker, which can be followed by the rest of the expression it ends:
+//
+//	<<EOF
+//	  name: ${name}
+//	EOF | kubectl(apply, -f, -)
+//
+// <<-EOF removes the indentation shared by the lines of the body and allows
+// the marker to be indented, and <<'EOF' has no interpolations or escape
+// sequences
+func (s *scanner) scanHeredoc(start *types.Pos) error {
+	s.advance(2)
+	t := &template{start: start}
+
+	if s.peek(0) == '-' {
+		t.trimIndent = true
+		s.advance(1)
+	}
+
+	if s.peek(0) == '\'' {
+		t.raw = true
+		s.advance(1)
+	}
+
+	markerStart := s.offset
+	for isLetter(s.peek(0)) || isDigit(s.peek(0)) {
+		s.advance(1)
+	}
+
+	t.marker = s.code[markerStart:s.offset]
+	if t.marker == "" {
+		return s.errorf(start, "expected a heredoc marker after <<")
+	}
+
+	if t.raw {
+		if s.peek(0) != '\'' {
+			return s.errorf(start, "unterminated heredoc marker")
+		}
+		s.advance(1)
+	}
+
+	s.emit("HEREDOC", start)
+
+	for s.peek(0) == ' ' || s.peek(0) == '\t' || s.peek(0) == '\r' {
+		s.advance(1)
+	}
+
+	if s.peek(0) != '\n' {
+		return s.errorf(s.pos(), "expected a new line after the heredoc marker")
+	}
+
+	s.advance(1)
+	t.bodyStart = s.offset
+	return s.scanStringPart(t, s.pos())
+}
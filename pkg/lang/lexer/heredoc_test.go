@@ -0,0 +1,90 @@
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLexHeredocs(t *testing.T) {
+	tests := []struct {
+		code string
+		body string
+	}{
+		{"<<EOF\na\nb\nEOF", "a\nb\n"},
+		{"<<EOF\n  a\nEOF", "  a\n"},
+		{"<<EOF\nEOF", ""},
+
+		// the marker must be a line of its own
+		{"<<EOF\nEOF is reached\nEOF", "EOF is reached\n"},
+		{"<<EOF\nEOFS\nEOF", "EOFS\n"},
+		{"<<EOF\nnot EOF\nEOF", "not EOF\n"},
+
+		// only the marker of <<- can be indented
+		{"<<EOF\n  EOF\nEOF", "  EOF\n"},
+		{"<<-EOF\n    a\n      b\n    EOF", "a\n  b\n"},
+
+		// no escapes or interpolations in <<'EOF'
+		{"<<'EOF'\n${x} \\n\nEOF", "${x} \\n\n"},
+		{"<<EOF\n\\${x} \\\\ \\n\nEOF", "${x} \\ \\n\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			if got := values(t, test.code); !reflect.DeepEqual(got, []string{test.body}) {
+				t.Fatalf("expected the body %q, got %q", test.body, got)
+			}
+		})
+	}
+}
+
+func TestLexHeredocEnd(t *testing.T) {
+	tests := []struct {
+		code   string
+		tokens string
+	}{
+		{"<<EOF\na\nEOF | kubectl(apply)", "HEREDOC STRING PUNCTUATOR:| IDENTIFIER:kubectl PUNCTUATOR:( IDENTIFIER:apply PUNCTUATOR:)"},
+		{"f(<<EOF\na\nEOF, x)", "IDENTIFIER:f PUNCTUATOR:( HEREDOC STRING PUNCTUATOR:, IDENTIFIER:x PUNCTUATOR:)"},
+		{"f(<<-EOF\n  a\n  EOF)", "IDENTIFIER:f PUNCTUATOR:( HEREDOC STRING PUNCTUATOR:)"},
+		{"<<EOF\na ${x}\nEOF\ny", "HEREDOC TEMPLATE_BEGIN IDENTIFIER:x TEMPLATE_END IDENTIFIER:y"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			got := []string{}
+			for _, token := range tokens(t, test.code) {
+				if tokenType, _, _ := strings.Cut(token, ":"); tokenType == "IDENTIFIER" || tokenType == "PUNCTUATOR" {
+					got = append(got, token)
+				} else {
+					got = append(got, tokenType)
+				}
+			}
+
+			if expected := strings.Split(test.tokens, " "); !reflect.DeepEqual(got, expected) {
+				t.Fatalf("expected %v, got %v", expected, got)
+			}
+		})
+	}
+}
+
+func TestLexHeredocErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		err  string
+	}{
+		{"<<EOF\na\n  EOF", "unterminated heredoc, expected EOF"},
+		{"<<EOF\na\nEOF is reached", "unterminated heredoc, expected EOF"},
+		{"<< EOF\nEOF", "expected a heredoc marker after <<"},
+		{"<<'EOF\nEOF", "unterminated heredoc marker"},
+		{"<<EOF x\nEOF", "expected a new line after the heredoc marker"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			_, err := Lex(test.code)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected an error with %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -2,84 +2,201 @@ package lexer
 
 import (
 	"fmt"
-	"regexp"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
 )
 
-type Pattern struct {
-	Name string
-	Re   *regexp.Regexp
+// Token types:
+// - KEYWORD
+// - IDENTIFIER
+// - BOOLEAN
+// - PUNCTUATOR
+// - INTEGER
+// - FLOAT
+// - STRING
+// - TEMPLATE_BEGIN, TEMPLATE_MIDDLE and TEMPLATE_END, see template
+// - HEREDOC, see scanHeredoc
+// - EOF, which is always the last token
+
+var keywords = map[string]bool{
+	"fn": true, "if": true, "else": true, "elif": true, "and": true,
+	"or": true, "not": true, "return": true, "import": true, "from": true,
+	"as": true, "for": true, "in": true, "break": true, "continue": true,
+	"sandbox": true, "retry": true, "while": true, "loop": true,
+}
+
+// operators are the punctuators that are longer than one character
+var operators = []string{
+	"==", "!=", ">=", "<=", "...", "..", ">>", "=>",
+	"+=", "-=", "*=", "/=", "%=", "++", "--",
 }
 
-// Name enum:
+const punctuators = "{}()[]<>,.:;+-/*%=|!"
 
-var patterns = []Pattern{
-	{Name: "WHITESPACE", Re: regexp.MustCompile(`^(\s+)`)},
-	{Name: "COMMENT", Re: regexp.MustCompile(`^(#.*\n)`)},
-	{Name: "KEYWORD", Re: regexp.MustCompile(`^(fn|if|else|elif|and|or|not|return|true|false|import|from|as|for|in|break|continue)\s`)},
-	{Name: "IDENTIFIER", Re: regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9-_]*)`)},
-	{Name: "PUNCTUATOR", Re: regexp.MustCompile(`^([{}()[\]<>,.;+-/*%=|!])`)},
-	{Name: "INTEGER", Re: regexp.MustCompile(`^([0-9]+)`)},
-	{Name: "FLOAT", Re: regexp.MustCompile(`^([0-9]+\.[0-9]+)`)},
-	{Name: "STRING", Re: regexp.MustCompile(`^"(\\.|[^"]*)"`)},
+type scanner struct {
+	code   string
+	offset int
+	line   int
+	column int
+	tokens []types.Node
+	// templates are the strings whose interpolations are being scanned
+	templates []*template
 }
 
-func Lex(code string) ([]types.Node, error) {
-	offset := 0
-	line := 0
-	column := 0
+func (s *scanner) pos() *types.Pos {
+	return &types.Pos{Line: s.line, Column: s.column, Offset: s.offset}
+}
 
-	tokens := []types.Node{}
+func (s *scanner) peek(n int) byte {
+	if s.offset+n >= len(s.code) {
+		return 0
+	}
+	return s.code[s.offset+n]
+}
 
-	for {
-		if offset >= len(code) {
-			break
+// advance moves the scanner n bytes forward, keeping track of the position
+func (s *scanner) advance(n int) {
+	for _, c := range s.code[s.offset : s.offset+n] {
+		if c == '\n' {
+			s.line++
+			s.column = 0
+		} else {
+			s.column++
 		}
+	}
+
+	s.offset += n
+}
+
+func (s *scanner) emit(tokenType string, start *types.Pos) *types.TokenNode {
+	token := &types.TokenNode{
+		BaseNode: types.BaseNode{Type: tokenType},
+		Image:    s.code[start.Offset:s.offset],
+		Pos:      start,
+		End:      s.pos(),
+	}
+	s.tokens = append(s.tokens, token)
+	return token
+}
+
+func (s *scanner) errorf(pos *types.Pos, format string, args ...any) error {
+	return fmt.Errorf("%s at %d:%d", fmt.Sprintf(format, args...), pos.Line+1, pos.Column+1)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func (s *scanner) scanIdentifier(start *types.Pos) {
+	// identifiers can have dashes so commands such as git-lfs can be called,
+	// but they do not end with one, and a dash before a digit is a minus:
+	// count-- and n-1
+	for isLetter(s.peek(0)) || isDigit(s.peek(0)) || (s.peek(0) == '-' && isLetter(s.peek(1))) {
+		s.advance(1)
+	}
+
+	image := s.code[start.Offset:s.offset]
+	if image == "true" || image == "false" {
+		s.emit("BOOLEAN", start)
+	} else if keywords[image] {
+		s.emit("KEYWORD", start)
+	} else {
+		s.emit("IDENTIFIER", start)
+	}
+}
 
-		for _, pattern := range patterns {
-			if match := pattern.Re.FindString(code[offset:]); match != "" {
-				if pattern.Name != "WHITESPACE" && pattern.Name != "COMMENT" {
-
-					image := match
-					if pattern.Name == "KEYWORD" {
-						image = match[:len(match)-1]
-					}
-
-					tokens = append(tokens, &types.TokenNode{
-						BaseNode: types.BaseNode{Type: pattern.Name},
-						Pos:      &types.Pos{Line: line, Column: column},
-						Image:    image,
-					})
-				}
-
-				if pattern.Name == "WHITESPACE" {
-					for _, c := range match {
-						if c == '\n' {
-							line++
-							column = 0
-						} else {
-							column++
-						}
-					}
-				} else {
-					for _, c := range match {
-						if c == '\n' {
-							line++
-							column = 0
-						} else {
-							column++
-						}
-					}
-				}
-
-				offset += len(match)
-				break
-			} else if pattern.Name == "STRING" {
-				return nil, fmt.Errorf("invalid string at %d:%d", line, column)
-			}
+func (s *scanner) scanNumber(start *types.Pos) {
+	for isDigit(s.peek(0)) {
+		s.advance(1)
+	}
+
+	// 1..5 is a range of integers, not a float
+	if s.peek(0) != '.' || !isDigit(s.peek(1)) {
+		s.emit("INTEGER", start)
+		return
+	}
+
+	s.advance(1)
+	for isDigit(s.peek(0)) {
+		s.advance(1)
+	}
+
+	s.emit("FLOAT", start)
+}
+
+func (s *scanner) scanPunctuator(start *types.Pos) error {
+	for _, op := range operators {
+		if strings.HasPrefix(s.code[s.offset:], op) {
+			s.advance(len(op))
+			s.emit("PUNCTUATOR", start)
+			return nil
 		}
 	}
 
-	return tokens, nil
+	if strings.IndexByte(punctuators, s.peek(0)) >= 0 {
+		s.advance(1)
+		s.emit("PUNCTUATOR", start)
+		return nil
+	}
+
+	c, _ := utf8.DecodeRuneInString(s.code[s.offset:])
+	return s.errorf(start, "unexpected character %q", c)
+}
+
+func (s *scanner) scanToken() error {
+	start := s.pos()
+	c := s.peek(0)
+
+	switch {
+	case isSpace(c):
+		s.advance(1)
+	case c == '#':
+		// comments run until the end of the line or the file
+		for s.offset < len(s.code) && s.peek(0) != '\n' {
+			s.advance(1)
+		}
+	case isLetter(c):
+		s.scanIdentifier(start)
+	case isDigit(c):
+		s.scanNumber(start)
+	case c == '"':
+		return s.scanString(start)
+	case c == '`':
+		return s.scanRawString(start)
+	case c == '<' && s.peek(1) == '<':
+		return s.scanHeredoc(start)
+	case (c == '{' || c == '}') && len(s.templates) > 0:
+		return s.scanInterpolationBrace(start)
+	default:
+		return s.scanPunctuator(start)
+	}
+
+	return nil
+}
+
+func Lex(code string) ([]types.Node, error) {
+	s := &scanner{code: code}
+
+	for s.offset < len(s.code) {
+		if err := s.scanToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.templates) > 0 {
+		return nil, s.errorf(s.templates[0].start, "unterminated string interpolation")
+	}
+
+	s.emit("EOF", s.pos())
+	return s.tokens, nil
 }
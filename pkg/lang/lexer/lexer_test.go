@@ -0,0 +1,91 @@
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tokens lexes code and returns its tokens as TYPE:image, without the EOF
+func tokens(t *testing.T, code string) []string {
+	t.Helper()
+
+	nodes, err := Lex(code)
+	if err != nil {
+		t.Fatalf("failed to lex %q: %v", code, err)
+	}
+
+	result := []string{}
+	for _, node := range nodes[:len(nodes)-1] {
+		result = append(result, node.GetType()+":"+node.GetImage())
+	}
+	return result
+}
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		code   string
+		tokens string
+	}{
+		// dashes
+		{"n-1", "IDENTIFIER:n PUNCTUATOR:- INTEGER:1"},
+		{"n - 1", "IDENTIFIER:n PUNCTUATOR:- INTEGER:1"},
+		{"xs[n-1]", "IDENTIFIER:xs PUNCTUATOR:[ IDENTIFIER:n PUNCTUATOR:- INTEGER:1 PUNCTUATOR:]"},
+		{"a-b", "IDENTIFIER:a-b"},
+		{"git-lfs", "IDENTIFIER:git-lfs"},
+		{"x2-y3", "IDENTIFIER:x2-y3"},
+		{"i--", "IDENTIFIER:i PUNCTUATOR:--"},
+		{"i-=2", "IDENTIFIER:i PUNCTUATOR:-= INTEGER:2"},
+		{"-1", "PUNCTUATOR:- INTEGER:1"},
+
+		// keywords and literals
+		{"if x in xs", "KEYWORD:if IDENTIFIER:x KEYWORD:in IDENTIFIER:xs"},
+		{"format true", "IDENTIFIER:format BOOLEAN:true"},
+		{"1..5", "INTEGER:1 PUNCTUATOR:.. INTEGER:5"},
+		{"1.5", "FLOAT:1.5"},
+		{"a <= b", "IDENTIFIER:a PUNCTUATOR:<= IDENTIFIER:b"},
+		{"x => x * 2", "IDENTIFIER:x PUNCTUATOR:=> IDENTIFIER:x PUNCTUATOR:* INTEGER:2"},
+		{"f(...xs)", "IDENTIFIER:f PUNCTUATOR:( PUNCTUATOR:... IDENTIFIER:xs PUNCTUATOR:)"},
+		{"x # a comment\ny", "IDENTIFIER:x IDENTIFIER:y"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			expected := strings.Split(test.tokens, " ")
+			if got := tokens(t, test.code); !reflect.DeepEqual(got, expected) {
+				t.Fatalf("expected %v, got %v", expected, got)
+			}
+		})
+	}
+}
+
+func TestLexPositions(t *testing.T) {
+	nodes, err := Lex("a\n  n-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos := nodes[3].GetPos()
+	if nodes[3].GetImage() != "1" || pos.Line != 1 || pos.Column != 4 {
+		t.Fatalf("expected 1 at 1:4, got %s at %d:%d", nodes[3].GetImage(), pos.Line, pos.Column)
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		err  string
+	}{
+		{"a @ b", `unexpected character '@' at 1:3`},
+		{`"abc`, "unterminated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			_, err := Lex(test.code)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected an error with %q, got %v", test.err, err)
+			}
+		})
+	}
+}
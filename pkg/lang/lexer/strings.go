@@ -0,0 +1,294 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// template is a string being scanned, which is split into parts around its
+// interpolations: "a ${x} b ${y} c" lexes as TEMPLATE_BEGIN x TEMPLATE_MIDDLE
+// y TEMPLATE_END
+type template struct {
+	start *types.Pos
+	// quote is the delimiter of the string, heredocs have a marker instead
+	quote  string
+	marker string
+	// bodyStart is where the body of a heredoc starts
+	bodyStart int
+	// raw strings have no escape sequences and no interpolations
+	raw bool
+	// trimIndent removes the indentation shared by the lines of the string
+	trimIndent bool
+	// depth counts the braces that are open in the current interpolation
+	depth int
+	parts []*types.TokenNode
+}
+
+// end returns the length of the end of the string if the scanner is at it,
+// or -1
+func (t *template) end(s *scanner) int {
+	rest := s.code[s.offset:]
+
+	if t.marker == "" {
+		if strings.HasPrefix(rest, t.quote) {
+			return len(t.quote)
+		}
+		return -1
+	}
+
+	// heredocs end at the line that is their marker, the marker can be followed
+	// by the rest of the expression it ends, which starts with a pipe, a comma
+	// or a closing parenthesis: EOF | kubectl(...). Only the marker of <<- can
+	// be indented
+	if s.offset != t.bodyStart && s.code[s.offset-1] != '\n' {
+		return -1
+	}
+
+	line := rest
+	if t.trimIndent {
+		line = strings.TrimLeft(rest, " \t")
+	}
+
+	if !strings.HasPrefix(line, t.marker) {
+		return -1
+	}
+
+	if next := strings.TrimLeft(line[len(t.marker):], " \t\r"); next != "" && !strings.ContainsRune("\n|,)", rune(next[0])) {
+		return -1
+	}
+
+	return len(rest) - len(line) + len(t.marker)
+}
+
+// scanString scans a double-quoted string, triple-quoted strings can span
+// lines and are dedented, see dedent
+func (s *scanner) scanString(start *types.Pos) error {
+	t := &template{start: start, quote: `"`}
+
+	if strings.HasPrefix(s.code[s.offset:], `"""`) {
+		t.quote = `"""`
+		t.trimIndent = true
+	}
+
+	s.advance(len(t.quote))
+	return s.scanStringPart(t, start)
+}
+
+// scanStringPart scans the text of a string until its end or the start of an
+// interpolation
+func (s *scanner) scanStringPart(t *template, start *types.Pos) error {
+	textStart := s.offset
+
+	for {
+		rest := s.code[s.offset:]
+
+		switch {
+		case rest == "" && t.marker != "":
+			return s.errorf(t.start, "unterminated heredoc, expected %s", t.marker)
+		case rest == "" || (t.quote == `"` && rest[0] == '\n'):
+			return s.errorf(t.start, "unterminated string")
+		case t.end(s) >= 0:
+			text := s.code[textStart:s.offset]
+			s.advance(t.end(s))
+
+			tokenType := "STRING"
+			if len(t.parts) > 0 {
+				tokenType = "TEMPLATE_END"
+			}
+
+			s.emitStringPart(t, tokenType, start, text)
+			return s.finishString(t)
+		case t.raw:
+			s.advance(1)
+		case strings.HasPrefix(rest, "${"):
+			text := s.code[textStart:s.offset]
+			s.advance(2)
+
+			tokenType := "TEMPLATE_BEGIN"
+			if len(t.parts) > 0 {
+				tokenType = "TEMPLATE_MIDDLE"
+			}
+
+			s.emitStringPart(t, tokenType, start, text)
+			s.templates = append(s.templates, t)
+			return nil
+		case rest[0] == '\\' && len(rest) > 1:
+			s.advance(2)
+		default:
+			s.advance(1)
+		}
+	}
+}
+
+func (s *scanner) emitStringPart(t *template, tokenType string, start *types.Pos, text string) {
+	token := s.emit(tokenType, start)
+	// the text is processed once all parts are scanned, see finishString
+	token.Value = text
+	t.parts = append(t.parts, token)
+}
+
+// finishString replaces the text of the parts of a string with their values
+func (s *scanner) finishString(t *template) error {
+	texts := make([]string, len(t.parts))
+	for i, part := range t.parts {
+		texts[i] = part.Value
+	}
+
+	if t.trimIndent {
+		// the parts are dedented together, with a placeholder in place of the
+		// interpolations
+		text := strings.Join(texts, "\x00")
+		if t.marker == "" {
+			text = dedent(text)
+		} else {
+			text = trimIndent(text)
+		}
+		texts = strings.Split(text, "\x00")
+	}
+
+	for i, part := range t.parts {
+		switch {
+		case t.raw:
+			part.Value = texts[i]
+		case t.marker != "":
+			part.Value = heredocEscapes.Replace(texts[i])
+		default:
+			value, err := unescape(texts[i])
+			if err != nil {
+				return s.errorf(part.Pos, "%v in string", err)
+			}
+			part.Value = value
+		}
+	}
+
+	return nil
+}
+
+// scanInterpolationBrace scans a brace inside an interpolation, the brace that
+// closes the interpolation resumes the string
+func (s *scanner) scanInterpolationBrace(start *types.Pos) error {
+	t := s.templates[len(s.templates)-1]
+
+	switch {
+	case s.peek(0) == '{':
+		t.depth++
+	case t.depth > 0:
+		t.depth--
+	default:
+		s.templates = s.templates[:len(s.templates)-1]
+		s.advance(1)
+		return s.scanStringPart(t, start)
+	}
+
+	return s.scanPunctuator(start)
+}
+
+// scanRawString scans a backtick string, which can span lines and has no
+// escape sequences or interpolations
+func (s *scanner) scanRawString(start *types.Pos) error {
+	s.advance(1)
+
+	end := strings.IndexByte(s.code[s.offset:], '`')
+	if end < 0 {
+		return s.errorf(start, "unterminated raw string")
+	}
+
+	s.advance(end + 1)
+	token := s.emit("STRING", start)
+	token.Value = s.code[start.Offset+1 : s.offset-1]
+	return nil
+}
+
+// unescape replaces the escape sequences of a string with the characters they
+// stand for
+func unescape(text string) (string, error) {
+	var value strings.Builder
+
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' {
+			value.WriteByte(text[i])
+			continue
+		}
+
+		i++
+		if i >= len(text) {
+			return "", fmt.Errorf("unfinished escape sequence")
+		}
+
+		switch text[i] {
+		case 'n':
+			value.WriteByte('\n')
+		case 't':
+			value.WriteByte('\t')
+		case 'r':
+			value.WriteByte('\r')
+		case '0':
+			value.WriteByte(0)
+		case '\\', '"', '\'', '`', '$':
+			value.WriteByte(text[i])
+		case 'u':
+			// \u{1F600}
+			end := strings.IndexByte(text[i:], '}')
+			if i+1 >= len(text) || text[i+1] != '{' || end < 0 {
+				return "", fmt.Errorf("invalid unicode escape, expected \\u{...}")
+			}
+
+			code, err := strconv.ParseUint(text[i+2:i+end], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid unicode code point %q", text[i+2:i+end])
+			}
+
+			value.WriteRune(rune(code))
+			i += end
+		default:
+			return "", fmt.Errorf("unknown escape sequence \\%c", text[i])
+		}
+	}
+
+	return value.String(), nil
+}
+
+// dedent drops the line breaks after the opening and before the closing quotes
+// of a multi-line string, and the indentation shared by all of its lines, so it
+// can be indented along with the code around it
+func dedent(text string) string {
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "\r"), "\n")
+	lines := strings.Split(text, "\n")
+
+	if len(lines) > 1 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return trimIndent(strings.Join(lines, "\n"))
+}
+
+// trimIndent removes the indentation shared by all lines of a text
+func trimIndent(text string) string {
+	lines := strings.Split(text, "\n")
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		width := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || width < indent {
+			indent = width
+		}
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = line[indent:]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,144 @@
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// values lexes code and returns the values of its string tokens
+func values(t *testing.T, code string) []string {
+	t.Helper()
+
+	nodes, err := Lex(code)
+	if err != nil {
+		t.Fatalf("failed to lex %q: %v", code, err)
+	}
+
+	result := []string{}
+	for _, node := range nodes {
+		if token := node.(*types.TokenNode); token.Type == "STRING" {
+			result = append(result, token.Value)
+		}
+	}
+	return result
+}
+
+func TestLexStrings(t *testing.T) {
+	tests := []struct {
+		code  string
+		value string
+	}{
+		// escapes
+		{`"a\tb\n"`, "a\tb\n"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\u{1F41A}"`, "\U0001F41A"},
+		{`"it's"`, "it's"},
+
+		// raw strings
+		{"`C:\\no\\escapes`", `C:\no\escapes`},
+		{"`two\nlines`", "two\nlines"},
+
+		// multi-line strings are dedented
+		{"\"\"\"\n    usage: deploy\n      --dry-run\n    \"\"\"", "usage: deploy\n  --dry-run"},
+		{"\"\"\"\n  a\n\n  b\n  \"\"\"", "a\n\nb"},
+		{`"""one line"""`, "one line"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			got := values(t, test.code)
+			if !reflect.DeepEqual(got, []string{test.value}) {
+				t.Fatalf("expected %q, got %q", test.value, got)
+			}
+		})
+	}
+}
+
+func TestLexStringErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		err  string
+	}{
+		{`"\q"`, `unknown escape sequence \q`},
+		{`"\u{zz}"`, "invalid unicode code point"},
+		{`"\u1F41A"`, "invalid unicode escape"},
+		{"\"a\nb\"", "unterminated string"},
+		{"`abc", "unterminated raw string"},
+		{`"""abc`, "unterminated string"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			_, err := Lex(test.code)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected an error with %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestLexTemplates(t *testing.T) {
+	tests := []struct {
+		code   string
+		tokens string
+	}{
+		{`"a ${x} b"`, "TEMPLATE_BEGIN IDENTIFIER:x TEMPLATE_END"},
+		{`"${a}${b}"`, "TEMPLATE_BEGIN IDENTIFIER:a TEMPLATE_MIDDLE IDENTIFIER:b TEMPLATE_END"},
+		{`"${m["k"]}"`, "TEMPLATE_BEGIN IDENTIFIER:m PUNCTUATOR:[ STRING PUNCTUATOR:] TEMPLATE_END"},
+		{`"${ {"k": 1} }"`, "TEMPLATE_BEGIN PUNCTUATOR:{ STRING PUNCTUATOR:: INTEGER:1 PUNCTUATOR:} TEMPLATE_END"},
+		{`"${"${x}"}"`, "TEMPLATE_BEGIN TEMPLATE_BEGIN IDENTIFIER:x TEMPLATE_END TEMPLATE_END"},
+		{`"\${x}"`, "STRING"},
+		{"`${x}`", "STRING"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			// strings and the parts of templates are compared by their types
+			nodes, err := Lex(test.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, node := range nodes[:len(nodes)-1] {
+				token := node.(*types.TokenNode)
+				if token.Type == "IDENTIFIER" || token.Type == "PUNCTUATOR" || token.Type == "INTEGER" {
+					got = append(got, token.Type+":"+token.Image)
+				} else {
+					got = append(got, token.Type)
+				}
+			}
+
+			if expected := strings.Split(test.tokens, " "); !reflect.DeepEqual(got, expected) {
+				t.Fatalf("expected %v, got %v", expected, got)
+			}
+		})
+	}
+
+	got := values(t, `"\${x}"`)
+	if !reflect.DeepEqual(got, []string{"${x}"}) {
+		t.Fatalf(`expected "${x}", got %q`, got)
+	}
+}
+
+func TestLexTemplateParts(t *testing.T) {
+	nodes, err := Lex(`"a ${x} b ${y}"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := []string{}
+	for _, node := range nodes {
+		if token := node.(*types.TokenNode); strings.HasPrefix(token.Type, "TEMPLATE_") {
+			parts = append(parts, token.Value)
+		}
+	}
+
+	if expected := []string{"a ", " b ", ""}; !reflect.DeepEqual(parts, expected) {
+		t.Fatalf("expected the parts %q, got %q", expected, parts)
+	}
+}
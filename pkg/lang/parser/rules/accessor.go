@@ -2,6 +2,7 @@ package rules
 
 import (
 	"go/ast"
+	"strings"
 
 	types "github.com/pouya-eghbali/posh/pkg/lang/parser/types"
 )
@@ -9,18 +10,55 @@ import (
 type DotNotation struct {
 	types.BaseNode
 	Accessors []types.Node `json:"accessors"`
+	IsValue   bool         `json:"isValue"`
+	// Enum is set for the values of enums: Env.dev
+	Enum string `json:"enum"`
+	// Package is set for the enum values and the variables of other modules:
+	// lib.EnvDev, lib.Config.Host
+	Package string `json:"package"`
+}
+
+func (d *DotNotation) GetPos() *types.Pos {
+	return d.Accessors[0].GetPos()
+}
+
+// exportedName returns the Go name of a field, PoSH fields are lowercase but
+// they need to be exported in Go to be accessible from other packages
+func exportedName(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
 }
 
 func (d *DotNotation) ToGoAst() ast.Node {
+	if d.Enum != "" && d.Package != "" {
+		return &ast.SelectorExpr{
+			X:   ast.NewIdent(d.Package),
+			Sel: ast.NewIdent(enumConst(d.Enum, d.Accessors[1].GetImage())),
+		}
+	}
+
+	if d.Enum != "" {
+		return ast.NewIdent(enumConst(d.Enum, d.Accessors[1].GetImage()))
+	}
+
 	// Start with the first identifier
 	var expr ast.Expr
 	expr = ast.NewIdent(d.Accessors[0].GetImage())
+	if d.Package != "" {
+		expr = &ast.SelectorExpr{X: ast.NewIdent(d.Package), Sel: ast.NewIdent(d.Accessors[0].GetImage())}
+	}
 
 	// Chain the accesses
 	for _, access := range d.Accessors[1:] {
+		name := access.GetImage()
+
+		// accessing a field of a value rather than a member of a package
+		if d.IsValue {
+			name = exportedName(name)
+		}
+
 		selector := &ast.SelectorExpr{
 			X:   expr,
-			Sel: ast.NewIdent(access.GetImage()),
+			Sel: ast.NewIdent(name),
 		}
 
 		expr = selector
@@ -34,10 +72,34 @@ func (d *DotNotation) StaticAnalysis(posh *types.PoshFile) {
 	// AND is one of the built-in libraries, we need to add it to the environment
 	if d.Accessors[0].GetType() == "IDENTIFIER" {
 		image := d.Accessors[0].GetImage()
-		if _, ok := posh.Environment.Get(image); !ok {
-			if _, ok := StdImports[image]; ok {
+		if valueType, ok := posh.Environment.Get(image); !ok {
+			if enum, ok := posh.Enums[image]; ok {
+				d.Enum = image
+				if len(d.Accessors) != 2 || !includes(enum.Values, d.Accessors[1].GetImage()) {
+					posh.Errorf(d.Accessors[1], "enum %s has no value %s", image, d.Accessors[1].GetImage())
+				}
+			} else if _, ok := StdImports[image]; ok {
 				posh.StdImports[image] = true
 			}
+		} else {
+			d.IsValue = !strings.HasPrefix(valueType, "module:")
+			d.Package = importedPackage(posh, image)
+		}
+	}
+
+	// check the fields of records: host.port
+	if d.IsValue {
+		valueType := typeOf(posh, d.Accessors[0])
+		for _, accessor := range d.Accessors[1:] {
+			record, ok := posh.Records[valueType]
+			if !ok {
+				break
+			}
+
+			if valueType, ok = fieldTypeOf(record, accessor.GetImage()); !ok {
+				posh.Errorf(accessor, "type %s has no field %s", record.Type, accessor.GetImage())
+				break
+			}
 		}
 	}
 }
@@ -19,114 +19,50 @@ var tokenMap = map[string]token.Token{
 	"-": token.SUB,
 	"*": token.MUL,
 	"/": token.QUO,
+	"%": token.REM,
 }
 
-func (a *ArithmeticNode) ToGoAst() ast.Node {
-	var lhs, rhs ast.Expr
-
-	if a.Lhs != nil {
-		lhs = a.Lhs.ToGoAst().(ast.Expr)
-	}
-
-	if a.Rhs != nil {
-		rhs = a.Rhs.ToGoAst().(ast.Expr)
-	}
-
-	return &ast.BinaryExpr{
-		X:  lhs,
-		Op: tokenMap[a.Op.GetImage()],
-		Y:  rhs,
-	}
-}
-
-func isOperator(value string) bool {
-	return value == "+" || value == "-" || value == "*" || value == "/"
+func (a *ArithmeticNode) GetPos() *types.Pos {
+	return a.Lhs.GetPos()
 }
 
-func MatchWrappedArithmetic(nodes []types.Node, offset int) types.Result {
-	start := offset
+func (a *ArithmeticNode) StaticAnalysis(posh *types.PoshFile) {
+	a.Lhs.StaticAnalysis(posh)
+	a.Rhs.StaticAnalysis(posh)
 
-	if nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != "(" {
-		return types.Result{FailedAt: &nodes[offset]}
-	}
-
-	offset++
-
-	var res types.Result
-	if res = MatchArithmetic(nodes, offset); res.End > res.Start {
-		node := ArithmeticNode{
-			BaseNode: types.BaseNode{
-				Type: "WRAPPED_ARITHMETIC",
-			},
-			Lhs: res.Node.(*ArithmeticNode).Lhs,
-			Op:  res.Node.(*ArithmeticNode).Op,
-			Rhs: res.Node.(*ArithmeticNode).Rhs,
-		}
-
-		if nodes[res.End].GetType() != "PUNCTUATOR" || nodes[res.End].GetImage() != ")" {
-			return types.Result{FailedAt: &nodes[res.End]}
+	// the remainder is only defined on integers
+	if a.Op.GetImage() == "%" {
+		for _, operand := range []types.Node{a.Lhs, a.Rhs} {
+			if operandType := typeOf(posh, operand); operandType != "" && operandType != "unknown" && !isIntegerType(operandType) {
+				posh.Errorf(a.Op, "operator %% is not defined on %s", operandType)
+				return
+			}
 		}
-
-		return types.Result{Node: &node, Start: start, End: res.End + 1}
 	}
-
-	return types.Result{FailedAt: res.FailedAt}
 }
 
-func MatchArithmetic(nodes []types.Node, offset int) types.Result {
-	start := offset
-
-	// We should recursively match the following:
-	// NUMERIC [OPERATOR NUMERIC]+
-	// We don't care about the order of the operators
-	// since Go will handle the precedence for us
-
-	// try to match NUMERIC
-	var res types.Result
-	if res = MatchNumeric(nodes, offset); res.End > res.Start {
-		offset = res.End
-	} else {
-		return types.Result{FailedAt: res.FailedAt}
-	}
-
-	node := ArithmeticNode{
-		BaseNode: types.BaseNode{
-			Type: "ARITHMETIC",
-		},
-		Lhs: res.Node,
-	}
-
-	// if there are no operators, this is not an arithmetic expression
-	if offset+1 >= len(nodes) || nodes[offset+1].GetType() != "PUNCTUATOR" || !isOperator(nodes[offset+1].GetImage()) {
-		return types.Result{FailedAt: &nodes[offset+1]}
-	}
+func (a *ArithmeticNode) ToGoAst() ast.Node {
+	return binaryExpr(valueToGoAst(a.Lhs), tokenMap[a.Op.GetImage()], valueToGoAst(a.Rhs))
+}
 
-	for {
-		if nodes[offset].GetType() != "PUNCTUATOR" || !isOperator(nodes[offset].GetImage()) {
-			break
-		}
+// Unary is a negated number: -x
+type Unary struct {
+	types.BaseNode
+	Op    types.Node `json:"op"`
+	Value types.Node `json:"value"`
+}
 
-		node.Op = nodes[offset]
-		offset++
+func (n *Unary) GetPos() *types.Pos {
+	return n.Op.GetPos()
+}
 
-		if res = MatchNumeric(nodes, offset); res.End > res.Start {
-			node.Rhs = res.Node
-			offset = res.End
-		} else {
-			return types.Result{FailedAt: res.FailedAt}
-		}
+func (n *Unary) StaticAnalysis(posh *types.PoshFile) {
+	n.Value.StaticAnalysis(posh)
+}
 
-		// if there's more operators, we need to create a new node
-		// and set the current node as the LHS
-		if offset+1 < len(nodes) && nodes[offset+1].GetType() == "PUNCTUATOR" && isOperator(nodes[offset+1].GetImage()) {
-			node = ArithmeticNode{
-				BaseNode: types.BaseNode{
-					Type: "ARITHMETIC",
-				},
-				Lhs: &node,
-			}
-		}
+func (n *Unary) ToGoAst() ast.Node {
+	return &ast.UnaryExpr{
+		Op: token.SUB,
+		X:  valueToGoAst(n.Value),
 	}
-
-	return types.Result{Node: &node, Start: start, End: offset}
 }
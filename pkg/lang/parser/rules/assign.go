@@ -3,74 +3,419 @@ package rules
 import (
 	"go/ast"
 	"go/token"
+	"strings"
 
 	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
 )
 
+// constKey marks the names of a scope that can not be changed, its value
+// tells where they come from: a const declared at 3:7
+func constKey(name string) string {
+	return "@const:" + name
+}
+
+// topLevelKey marks the variables declared at the top level of a file, the
+// other names of the top level scope such as functions are shadowed instead
+// of changed by assignments
+func topLevelKey(name string) string {
+	return "@top:" + name
+}
+
+// isVariable tells if an assignment to an existing name changes it
+func isVariable(posh *types.PoshFile, name string, scope int) bool {
+	_, isTopLevel := posh.Environment.Get(topLevelKey(name))
+	return scope > 0 || isTopLevel
+}
+
+// assignOperators are the operators that change a variable in place
+var assignOperators = map[string]token.Token{
+	"+=": token.ADD_ASSIGN,
+	"-=": token.SUB_ASSIGN,
+	"*=": token.MUL_ASSIGN,
+	"/=": token.QUO_ASSIGN,
+	"%=": token.REM_ASSIGN,
+	"++": token.INC,
+	"--": token.DEC,
+}
+
+func isNumericType(name string) bool {
+	return isIntegerType(name) || strings.HasPrefix(name, "float")
+}
+
+// isIntegerType tells if a type has the % operator
+func isIntegerType(name string) bool {
+	return strings.HasPrefix(name, "int") || strings.HasPrefix(name, "uint")
+}
+
 // TODO: Needs plug and unplug
 type Assignment struct {
 	types.BaseNode
+	// Keyword is const or var for declarations: const name = value
+	Keyword    types.Node `json:"keyword"`
 	Identifier types.Node `json:"identifier"`
-	Value      types.Node `json:"value"`
-	ValueType  string     `json:"valueType"`
+	// Declared is the type of a declaration: var count int = 0
+	Declared  *TypeNode  `json:"declared"`
+	Value     types.Node `json:"value"`
+	ValueType string     `json:"valueType"`
+	// IsReassignment is set when the assignment changes an existing variable
+	IsReassignment bool `json:"isReassignment"`
+	// Operator is set when the variable is changed in place: total += size,
+	// count++ has no value
+	Operator types.Node `json:"operator"`
+}
+
+func (a *Assignment) GetPos() *types.Pos {
+	if a.Keyword != nil {
+		return a.Keyword.GetPos()
+	}
+
+	return a.Identifier.GetPos()
 }
 
 func (a *Assignment) ToGoAst() ast.Node {
 	var value ast.Expr
+	if a.Value != nil {
+		value = valueToGoAst(a.Value)
+	}
+
+	if a.Operator != nil {
+		tok := assignOperators[a.Operator.GetImage()]
+		if tok == token.INC || tok == token.DEC {
+			return &ast.IncDecStmt{
+				X:   a.Identifier.ToGoAst().(ast.Expr),
+				Tok: tok,
+			}
+		}
+
+		return &ast.AssignStmt{
+			Lhs: []ast.Expr{a.Identifier.ToGoAst().(ast.Expr)},
+			Tok: tok,
+			Rhs: []ast.Expr{value},
+		}
+	}
 
-	if a.Value != nil && a.Value.GetType() == "PIPE" {
-		// we need to add .Wait().ToString() to the end of the pipe
-		value = &ast.CallExpr{
-			Fun: &ast.SelectorExpr{
-				X:   a.Value.ToGoAst().(ast.Expr),
-				Sel: &ast.Ident{Name: "Wait"},
+	// a var without a value that is declared before a block starts again at
+	// the zero value, see blockBody: count = *new(int)
+	if a.Declared != nil && a.IsReassignment && value == nil {
+		value = &ast.StarExpr{
+			X: &ast.CallExpr{
+				Fun:  &ast.Ident{Name: "new"},
+				Args: []ast.Expr{a.Declared.ToGoAst().(ast.Expr)},
 			},
-			Args: []ast.Expr{},
+		}
+	}
+
+	// a declared type needs a var: var count int = 0
+	if a.Declared != nil && !a.IsReassignment {
+		spec := &ast.ValueSpec{
+			Names: []*ast.Ident{{Name: a.Identifier.GetImage()}},
+			Type:  a.Declared.ToGoAst().(ast.Expr),
 		}
 
-		value = &ast.CallExpr{
-			Fun: &ast.SelectorExpr{
-				X:   value,
-				Sel: &ast.Ident{Name: "ToString"},
+		if value != nil {
+			spec.Values = []ast.Expr{value}
+		}
+
+		return &ast.DeclStmt{
+			Decl: &ast.GenDecl{
+				Tok:   token.VAR,
+				Specs: []ast.Spec{spec},
 			},
-			Args: []ast.Expr{},
 		}
-	} else if a.Value != nil {
-		value = a.Value.ToGoAst().(ast.Expr)
+	}
+
+	// items of lists and maps and fields already exist: m[k] = v
+	tok := token.DEFINE
+	if isElement(a.Identifier) || a.IsReassignment {
+		tok = token.ASSIGN
 	}
 
 	return &ast.AssignStmt{
 		Lhs: []ast.Expr{a.Identifier.ToGoAst().(ast.Expr)},
-		Tok: token.DEFINE,
+		Tok: tok,
 		Rhs: []ast.Expr{value},
 	}
 }
 
 func (a *Assignment) ToGoStatementAst() ast.Stmt {
-	return a.ToGoAst().(*ast.AssignStmt)
+	return a.ToGoAst().(ast.Stmt)
 }
 
 func (a *Assignment) StaticAnalysis(posh *types.PoshFile) {
-	posh.Environment.Set(a.Identifier.GetImage(), "unknown")
-	a.Value.StaticAnalysis(posh)
+	if isElement(a.Identifier) {
+		a.elementAnalysis(posh)
+		return
+	}
+
+	// an arrow function takes its type from the variable
+	if a.Declared != nil {
+		expectType(a.Value, a.Declared.Name)
+	} else if valueType, ok := posh.Environment.Get(a.Identifier.GetImage()); ok && a.Identifier.GetType() == "IDENTIFIER" {
+		expectType(a.Value, valueType)
+	}
+
+	if a.Value != nil {
+		a.Value.StaticAnalysis(posh)
+	}
+
+	if a.Operator != nil {
+		a.variableOperatorAnalysis(posh)
+		return
+	}
+
+	name := a.Identifier.GetImage()
+	valueType := typeOf(posh, a.Value)
+	variableType, scope, exists := posh.Environment.Lookup(name)
+
+	if tuple := tupleTypes(valueType); tuple != nil {
+		posh.Errorf(a.Value, "cannot assign %d values to a name, use as many names: a, b = ...", len(tuple))
+		return
+	}
+
+	if a.Keyword == nil && exists && isVariable(posh, name, scope) {
+		a.IsReassignment = true
+
+		if origin, constScope, ok := posh.Environment.Lookup(constKey(name)); ok && constScope == scope {
+			posh.Errorf(a.Identifier, "cannot assign to %s, it is %s", name, origin)
+		} else if valueType != "" && variableType != "unknown" && valueType != variableType {
+			posh.Errorf(a.Value, "cannot assign %s to %s of type %s", valueType, name, variableType)
+		}
+		return
+	}
+
+	if a.Keyword != nil && exists && scope == posh.Environment.Cursor {
+		posh.Errorf(a.Identifier, "%s is already declared in this scope", name)
+	}
+
+	if a.Declared != nil && a.Value != nil {
+		checkType(posh, []types.Node{a.Value}, a.Declared.Name, "value of type")
+	}
+
+	if a.Declared != nil {
+		valueType = a.Declared.Name
+	}
+
+	if valueType == "" {
+		valueType = "unknown"
+	}
+	posh.Environment.Set(name, valueType)
+
+	if a.Keyword != nil && a.Keyword.GetImage() == "const" {
+		posh.Environment.Set(constKey(name), "a const declared at "+a.Identifier.GetPos().String())
+	}
+
+	if posh.Environment.Cursor == 0 {
+		a.topLevelAnalysis(posh, valueType)
+	}
 }
 
-func MatchAssignment(nodes []types.Node, offset int) types.Result {
-	start := offset
+// isElement tells if the target of an assignment is an item of a list or a
+// map or a field of a record, which exist already: m[k] = v, h.port = 80
+func isElement(node types.Node) bool {
+	switch node.(type) {
+	case *Index, *Field, *DotNotation:
+		return true
+	}
 
-	if nodes[offset].GetType() != "IDENTIFIER" {
+	return false
+}
+
+// elementAnalysis checks the assignments to the items of lists and maps and
+// to the fields of records
+func (a *Assignment) elementAnalysis(posh *types.PoshFile) {
+	a.Identifier.StaticAnalysis(posh)
+
+	// the members of packages and modules and the values of enums are not
+	// variables
+	if dot, ok := a.Identifier.(*DotNotation); ok && !dot.IsValue {
+		posh.Errorf(dot, "cannot assign to %s, it is not a variable", dot.Accessors[0].GetImage())
+		return
+	}
+
+	targetType := typeOf(posh, a.Identifier)
+	if a.Value != nil {
+		expectType(a.Value, targetType)
+		a.Value.StaticAnalysis(posh)
+	}
+
+	if a.Operator != nil {
+		a.operatorAnalysis(posh, targetType)
+		return
+	}
+
+	what := "field of type"
+	if index, ok := a.Identifier.(*Index); ok {
+		what = "list item"
+		if _, value := mapTypes(typeOf(posh, index.Value)); value != "" {
+			what = "map value"
+		}
+	}
+
+	if targetType != "" {
+		checkType(posh, []types.Node{a.Value}, targetType, what)
+	}
+}
+
+// topLevelAnalysis declares a variable of the top level of a file as a Go
+// package variable, capitalized variables are exported like functions
+func (a *Assignment) topLevelAnalysis(posh *types.PoshFile, valueType string) {
+	name := a.Identifier.GetImage()
+	posh.Environment.Set(topLevelKey(name), "true")
+
+	spec := &ast.ValueSpec{
+		Names: []*ast.Ident{{Name: name}},
+	}
+
+	if a.Declared != nil {
+		spec.Type = a.Declared.ToGoAst().(ast.Expr)
+	}
+
+	if a.Value != nil {
+		spec.Values = []ast.Expr{valueToGoAst(a.Value)}
+	}
+
+	posh.TopLevelAssignments = append(posh.TopLevelAssignments, spec)
+
+	if firstLetter := name[0]; firstLetter >= 'A' && firstLetter <= 'Z' {
+		posh.Exports[name] = types.Export{Type: valueType}
+	}
+}
+
+// variableOperatorAnalysis checks that the variable that an operator changes
+// exists
+func (a *Assignment) variableOperatorAnalysis(posh *types.PoshFile) {
+	name := a.Identifier.GetImage()
+	variableType, scope, exists := posh.Environment.Lookup(name)
+	if !exists || !isVariable(posh, name, scope) {
+		posh.Errorf(a.Identifier, "%s is not declared, %s needs an existing variable", name, a.Operator.GetImage())
+		return
+	}
+
+	if origin, constScope, ok := posh.Environment.Lookup(constKey(name)); ok && constScope == scope {
+		posh.Errorf(a.Identifier, "cannot assign to %s, it is %s", name, origin)
+		return
+	}
+
+	a.operatorAnalysis(posh, variableType)
+}
+
+// operatorAnalysis checks that the type of the variable has the operator, +=
+// also joins strings and %= only takes integers
+func (a *Assignment) operatorAnalysis(posh *types.PoshFile, variableType string) {
+	op := a.Operator.GetImage()
+
+	if variableType == "" || variableType == "unknown" {
+		return
+	}
+
+	hasOperator := isNumericType(variableType) || op == "+=" && variableType == "string"
+	if op == "%=" {
+		hasOperator = isIntegerType(variableType)
+	}
+
+	if !hasOperator {
+		posh.Errorf(a.Operator, "operator %s is not defined on %s", op, variableType)
+		return
+	}
+
+	if a.Value != nil {
+		checkType(posh, []types.Node{a.Value}, variableType, "operand of type")
+	}
+}
+
+// MatchDeclaration matches the declarations of the top level of a file:
+// const REGION = "eu-1"
+func MatchDeclaration(nodes []types.Node, offset int) types.Result {
+	if !isDeclarationKeyword(nodes, offset) {
 		return types.Result{FailedAt: &nodes[offset]}
 	}
 
+	return MatchAssignment(nodes, offset)
+}
+
+// isDeclarationKeyword tells if a node starts a declaration, const and var are
+// not keywords so commands can still take them as flags: terraform(-var, "a=b")
+func isDeclarationKeyword(nodes []types.Node, offset int) bool {
+	image := nodes[offset].GetImage()
+	return nodes[offset].GetType() == "IDENTIFIER" && (image == "const" || image == "var") &&
+		nodes[offset+1].GetType() == "IDENTIFIER"
+}
+
+func MatchAssignment(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for one of the following:
+	// - (const | var)? IDENTIFIER = EXPRESSION
+	// - (const | var) IDENTIFIER TYPE = EXPRESSION
+	// - var IDENTIFIER TYPE
+	// - IDENTIFIER ([ EXPRESSION ] | . IDENTIFIER)* = EXPRESSION
+	// - IDENTIFIER ([ EXPRESSION ] | . IDENTIFIER)* (+= | -= | *= | /= | %=) EXPRESSION
+	// - IDENTIFIER ([ EXPRESSION ] | . IDENTIFIER)* (++ | --)
+
 	node := Assignment{
 		BaseNode: types.BaseNode{
 			Type: "ASSIGNMENT",
 		},
-		Identifier: nodes[offset],
 	}
 
+	if isDeclarationKeyword(nodes, offset) {
+		node.Keyword = nodes[offset]
+		offset++
+	}
+
+	if nodes[offset].GetType() != "IDENTIFIER" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node.Identifier = nodes[offset]
 	offset++
 
+	// assignments to the items of lists and maps and to the fields of
+	// records: xs[i] = x, h.port = 80
+	if node.Keyword == nil && (isPunctuator(nodes[offset], "[") || isPunctuator(nodes[offset], ".")) {
+		res := matchPostfix(nodes, start)
+		if res.End <= res.Start || !isElement(res.Node) {
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+
+		node.Identifier = res.Node
+		offset = res.End
+	}
+
+	// try to match the operators that change the variable in place
+	if _, ok := assignOperators[nodes[offset].GetImage()]; ok && node.Keyword == nil && nodes[offset].GetType() == "PUNCTUATOR" {
+		node.Operator = nodes[offset]
+		offset++
+
+		if node.Operator.GetImage() == "++" || node.Operator.GetImage() == "--" {
+			return types.Result{Node: &node, Start: start, End: offset}
+		}
+
+		res := MatchExpr(nodes, offset)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node.Value = res.Node
+		return types.Result{Node: &node, Start: start, End: res.End}
+	}
+
+	// try to match the declared type
+	if node.Keyword != nil && !isPunctuator(nodes[offset], "=") {
+		res := MatchType(nodes, offset)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node.Declared = res.Node.(*TypeNode)
+		offset = res.End
+
+		// var count int starts with the zero value
+		if node.Keyword.GetImage() == "var" && !isPunctuator(nodes[offset], "=") {
+			return types.Result{Node: &node, Start: start, End: offset}
+		}
+	}
+
 	if nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != "=" {
 		return types.Result{FailedAt: &nodes[offset]}
 	}
@@ -87,3 +432,212 @@ func MatchAssignment(nodes []types.Node, offset int) types.Result {
 
 	return types.Result{Node: &node, Start: start, End: offset}
 }
+
+// Destructuring assigns several names at once, from the values of a tuple or
+// from a value per name: host, port = parse(address)
+type Destructuring struct {
+	types.BaseNode
+	Identifiers []types.Node `json:"identifiers"`
+	Values      []types.Node `json:"values"`
+	// IsNew is set for the names that are declared by the assignment, and
+	// Types are the types of all of the names
+	IsNew []bool   `json:"isNew"`
+	Types []string `json:"types"`
+}
+
+func (n *Destructuring) GetPos() *types.Pos {
+	return n.Identifiers[0].GetPos()
+}
+
+// valueTypes returns the types of the values, it is nil when they are not known
+func (n *Destructuring) valueTypes(posh *types.PoshFile) []string {
+	if len(n.Values) > 1 {
+		valueTypes := []string{}
+		for _, value := range n.Values {
+			valueTypes = append(valueTypes, typeOf(posh, value))
+		}
+
+		return valueTypes
+	}
+
+	valueType := typeOf(posh, n.Values[0])
+	if tuple := tupleTypes(valueType); tuple != nil {
+		return tuple
+	}
+
+	// value, ok = m[key] also tells if the key exists
+	if index, ok := n.Values[0].(*Index); ok && len(n.Identifiers) == 2 {
+		if _, value := mapTypes(typeOf(posh, index.Value)); value != "" {
+			return []string{value, "bool"}
+		}
+	}
+
+	if valueType == "" {
+		return nil
+	}
+
+	return []string{valueType}
+}
+
+func (n *Destructuring) StaticAnalysis(posh *types.PoshFile) {
+	for _, value := range n.Values {
+		value.StaticAnalysis(posh)
+	}
+
+	valueTypes := n.valueTypes(posh)
+	if valueTypes != nil && len(valueTypes) != len(n.Identifiers) {
+		posh.Errorf(n.Values[0], "assignment mismatch: %d names but %d values", len(n.Identifiers), len(valueTypes))
+		return
+	}
+
+	seen := map[string]bool{}
+	n.IsNew = make([]bool, len(n.Identifiers))
+	n.Types = make([]string, len(n.Identifiers))
+
+	for i, identifier := range n.Identifiers {
+		name := identifier.GetImage()
+		if name == "_" {
+			continue
+		}
+
+		if seen[name] {
+			posh.Errorf(identifier, "%s is assigned more than once", name)
+		}
+		seen[name] = true
+
+		valueType := "unknown"
+		if valueTypes != nil && valueTypes[i] != "" {
+			valueType = valueTypes[i]
+		}
+
+		variableType, scope, exists := posh.Environment.Lookup(name)
+		if !exists || !isVariable(posh, name, scope) {
+			n.IsNew[i] = true
+			n.Types[i] = valueType
+			posh.Environment.Set(name, valueType)
+			continue
+		}
+
+		n.Types[i] = variableType
+		if origin, constScope, ok := posh.Environment.Lookup(constKey(name)); ok && constScope == scope {
+			posh.Errorf(identifier, "cannot assign to %s, it is %s", name, origin)
+		} else if valueType != "unknown" && variableType != "unknown" && valueType != variableType {
+			posh.Errorf(identifier, "cannot assign %s to %s of type %s", valueType, name, variableType)
+		}
+	}
+
+	// the new names are declared before the assignment when some of the names
+	// exist, see ToGoStatements
+	if !n.declaresAll() {
+		for i, identifier := range n.Identifiers {
+			if n.IsNew[i] && n.Types[i] == "unknown" {
+				posh.Errorf(identifier, "the type of %s is unknown, declare it first: var %s TYPE", identifier.GetImage(), identifier.GetImage())
+			}
+		}
+	}
+}
+
+// declaresAll tells if all of the names that are not _ are new
+func (n *Destructuring) declaresAll() bool {
+	declares := false
+	for i, identifier := range n.Identifiers {
+		if identifier.GetImage() == "_" {
+			continue
+		}
+
+		if !n.IsNew[i] {
+			return false
+		}
+
+		declares = true
+	}
+
+	return declares
+}
+
+func (n *Destructuring) ToGoStatements() []ast.Stmt {
+	stmts := []ast.Stmt{}
+
+	assign := &ast.AssignStmt{Tok: token.ASSIGN}
+	for _, value := range n.Values {
+		assign.Rhs = append(assign.Rhs, valueToGoAst(value))
+	}
+
+	// := would shadow the names that exist in outer blocks, so when only some
+	// of the names are new they are declared first: var port int
+	for i, identifier := range n.Identifiers {
+		assign.Lhs = append(assign.Lhs, &ast.Ident{Name: identifier.GetImage()})
+
+		if n.IsNew[i] && !n.declaresAll() {
+			stmts = append(stmts, &ast.DeclStmt{
+				Decl: &ast.GenDecl{
+					Tok: token.VAR,
+					Specs: []ast.Spec{
+						&ast.ValueSpec{
+							Names: []*ast.Ident{{Name: identifier.GetImage()}},
+							Type:  typeExpr(n.Types[i]),
+						},
+					},
+				},
+			})
+		}
+	}
+
+	if n.declaresAll() {
+		assign.Tok = token.DEFINE
+	}
+
+	return append(stmts, assign)
+}
+
+func MatchDestructuring(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// IDENTIFIER (, IDENTIFIER)+ = EXPRESSION (, EXPRESSION)*
+
+	node := Destructuring{
+		BaseNode: types.BaseNode{
+			Type: "DESTRUCTURING",
+		},
+	}
+
+	for {
+		if nodes[offset].GetType() != "IDENTIFIER" {
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+
+		node.Identifiers = append(node.Identifiers, nodes[offset])
+		offset++
+
+		if !isPunctuator(nodes[offset], ",") {
+			break
+		}
+
+		offset++
+	}
+
+	if len(node.Identifiers) < 2 || !isPunctuator(nodes[offset], "=") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	for {
+		res := MatchExpr(nodes, offset)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node.Values = append(node.Values, res.Node)
+		offset = res.End
+
+		if !isPunctuator(nodes[offset], ",") {
+			break
+		}
+
+		offset++
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset}
+}
@@ -0,0 +1,62 @@
+package rules
+
+import "testing"
+
+// operatorProgram returns a program that changes the variables of a function
+// with stmt
+func operatorProgram(stmt string) []string {
+	return []string{`fn f(n int, x float64, s string, xs []int, m map[string]int) void {
+  ` + stmt + `
+  io.Println(n, x, s, xs, m)
+}`}
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	tests := []struct {
+		stmt string
+		goIs string
+	}{
+		{"n += 2", "n += 2"},
+		{"n -= 2", "n -= 2"},
+		{"n *= 2", "n *= 2"},
+		{"n /= 2", "n /= 2"},
+		{"n %= 2", "n %= 2"},
+		{"n++", "n++"},
+		{"n--", "n--"},
+		{"x += 1.5", "x += 1.5"},
+		{"x /= 2.0", "x /= 2.0"},
+		{`s += "!"`, `s += "!"`},
+		{"xs[0] += 1", "xs[0] += 1"},
+		{`m["a"]++`, `m["a"]++`},
+		{"n = n % 3", "n = n % 3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.stmt, func(t *testing.T) {
+			expectGo(t, operatorProgram(test.stmt), test.goIs)
+		})
+	}
+}
+
+func TestCompoundAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		stmt string
+		err  string
+	}{
+		{"x %= 2.0", "operator %= is not defined on float64"},
+		{`s -= "a"`, "operator -= is not defined on string"},
+		{"s++", "operator ++ is not defined on string"},
+		{"n += 1.5", "operand of type int"},
+		{"y += 1", "y is not declared, += needs an existing variable"},
+		{"const c = 1\n  c++", "cannot assign to c, it is a const declared at"},
+		{"x = x % 2.0", "operator % is not defined on float64"},
+		{"n = 7 % x", "operator % is not defined on float64"},
+		{`n = s % 2`, "operator % is not defined on string"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.stmt, func(t *testing.T) {
+			expectError(t, operatorProgram(test.stmt), test.err)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// blockKey marks the scope of the body of a block that runs in a function
+// literal, such as retry and sandbox, its value is the name of the block
+const blockKey = "@block"
+
+// enclosingBlock returns the name of the block that runs in a function literal
+// that the code being analyzed is inside of, function values inside of the
+// block run on their own
+func enclosingBlock(posh *types.PoshFile) (string, bool) {
+	name, blockScope, ok := posh.Environment.Lookup(blockKey)
+	_, funcScope, _ := posh.Environment.Lookup(returnKey)
+	return name, ok && blockScope > funcScope
+}
+
+// hoistingBlock is implemented by the blocks that run in a function literal,
+// hoist returns the variables that are declared before the block and leaves
+// them to the block around it
+type hoistingBlock interface {
+	hoist() []types.Param
+}
+
+// blockBody analyzes the body of a block that runs in a function literal.
+// The variables that the body declares are declared before the block instead,
+// so they are still visible after it, see blockStatements. It returns their
+// names and types.
+func blockBody(posh *types.PoshFile, name string, body *FunctionBody) []types.Param {
+	// break and continue can not leave the function literal
+	loops := posh.Loops
+	posh.Loops = nil
+
+	posh.Environment.PushScope()
+	posh.Environment.Set(blockKey, name)
+	body.StaticAnalysis(posh)
+	scope := posh.Environment.Scopes[posh.Environment.Cursor]
+	posh.Environment.PopScope()
+
+	posh.Loops = loops
+
+	// the declarations of the body change the variables instead, and so do
+	// the blocks inside of it
+	variables := []types.Param{}
+	declare := func(identifier types.Node) {
+		variable := types.Param{Name: identifier.GetImage(), Type: scope[identifier.GetImage()]}
+		if variable.Type == "unknown" {
+			posh.Errorf(identifier, "the type of %s is unknown, declare it before the %s block: var %s TYPE", variable.Name, name, variable.Name)
+		}
+
+		variables = append(variables, variable)
+	}
+
+	for _, node := range body.Content {
+		switch n := node.(type) {
+		case *Assignment:
+			if n.Identifier.GetType() == "IDENTIFIER" && n.Operator == nil && !n.IsReassignment {
+				n.IsReassignment = true
+				declare(n.Identifier)
+			}
+		case *Destructuring:
+			for i, identifier := range n.Identifiers {
+				if n.IsNew[i] {
+					n.IsNew[i] = false
+					declare(identifier)
+				}
+			}
+		case hoistingBlock:
+			variables = append(variables, n.hoist()...)
+		}
+	}
+
+	for _, variable := range variables {
+		posh.Environment.Set(variable.Name, variable.Type)
+		if origin, ok := scope[constKey(variable.Name)]; ok {
+			posh.Environment.Set(constKey(variable.Name), origin)
+		}
+	}
+
+	return variables
+}
+
+// blockStatements returns the declarations of the variables of a block that
+// runs in a function literal followed by the call that runs it:
+//
+//	var host string
+//	exec.Sandbox(limits, func() { host = ... })
+func blockStatements(variables []types.Param, call *ast.CallExpr) []ast.Stmt {
+	stmts := []ast.Stmt{}
+	for _, variable := range variables {
+		stmts = append(stmts, &ast.DeclStmt{
+			Decl: &ast.GenDecl{
+				Tok: token.VAR,
+				Specs: []ast.Spec{
+					&ast.ValueSpec{
+						Names: []*ast.Ident{{Name: variable.Name}},
+						Type:  typeExpr(variable.Type),
+					},
+				},
+			},
+		})
+	}
+
+	return append(stmts, &ast.ExprStmt{X: call})
+}
@@ -0,0 +1,133 @@
+package rules
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// Builtin is a function of the PoSH runtime that can be called without
+// importing it first
+type Builtin struct {
+	// Package is empty for the builtins of Go
+	Package string
+	Name    string
+	// Shell builtins run a script and take a RunContext like a command
+	Shell bool
+}
+
+var Builtins = map[string]Builtin{
+	"exit":     {Package: "std", Name: "Exit"},
+	"sleep":    {Package: "std", Name: "Sleep"},
+	"complete": {Package: "exec", Name: "Complete"},
+	"sh":       {Package: "exec", Name: "Sh", Shell: true},
+	"bash":     {Package: "exec", Name: "Bash", Shell: true},
+	"sorted":   {Package: "std", Name: "Sorted"},
+	"len":      {Name: "len"},
+	"append":   {Name: "append"},
+	"delete":   {Name: "delete"},
+}
+
+func (b *Builtin) ToGoAst() ast.Node {
+	if b.Package == "" {
+		return &ast.Ident{Name: b.Name}
+	}
+
+	return &ast.SelectorExpr{
+		X:   &ast.Ident{Name: b.Package},
+		Sel: &ast.Ident{Name: b.Name},
+	}
+}
+
+// ShellVar passes a variable to a shell script as an environment variable:
+// sh("echo $name", name) becomes exec.Sh(ctx, "echo $name", exec.Env("name", name))
+type ShellVar struct {
+	types.BaseNode
+	Identifier types.Node `json:"identifier"`
+}
+
+func (n *ShellVar) ToGoAst() ast.Node {
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   &ast.Ident{Name: "exec"},
+			Sel: &ast.Ident{Name: "Env"},
+		},
+		Args: []ast.Expr{
+			&ast.BasicLit{
+				Kind:  token.STRING,
+				Value: fmt.Sprintf(`"%s"`, n.Identifier.GetImage()),
+			},
+			n.Identifier.ToGoAst().(ast.Expr),
+		},
+	}
+}
+
+// builtinArgs checks the arguments of the builtins that work on lists and
+// maps, the arguments of unknown types are left for Go to check
+func builtinArgs(posh *types.PoshFile, call *FunctionCall) {
+	name := call.Callable.GetImage()
+	argTypes := []string{}
+	for _, arg := range call.Args {
+		argTypes = append(argTypes, typeOf(posh, arg))
+	}
+
+	switch name {
+	case "len":
+		if len(call.Args) != 1 {
+			posh.Errorf(call.Callable, "len takes one argument")
+		} else if _, value := mapTypes(argTypes[0]); argTypes[0] != "" && argTypes[0] != "string" && value == "" && elemType(argTypes[0]) == "" {
+			posh.Errorf(call.Args[0], "cannot use len with %s", argTypes[0])
+		}
+	case "append":
+		if len(call.Args) == 0 {
+			posh.Errorf(call.Callable, "append needs a list")
+		} else if itemType := elemType(argTypes[0]); argTypes[0] != "" && itemType == "" {
+			posh.Errorf(call.Args[0], "cannot append to %s", argTypes[0])
+		} else if itemType != "" && len(call.Args) == 2 && isSpread(call.Args[1]) {
+			// append(xs, ...ys) appends the items of a list
+			checkType(posh, call.Args[1:], argTypes[0], "list")
+		} else if itemType != "" {
+			checkType(posh, call.Args[1:], itemType, "list item")
+		}
+	case "delete":
+		if len(call.Args) != 2 {
+			posh.Errorf(call.Callable, "delete takes a map and a key")
+		} else if key, value := mapTypes(argTypes[0]); argTypes[0] != "" && value == "" {
+			posh.Errorf(call.Args[0], "cannot delete from %s", argTypes[0])
+		} else if value != "" {
+			checkType(posh, call.Args[1:], key, "map key")
+		}
+	case "sorted":
+		if len(call.Args) != 1 {
+			posh.Errorf(call.Callable, "sorted takes one argument")
+		} else if _, value := mapTypes(argTypes[0]); argTypes[0] != "" && value == "" {
+			posh.Errorf(call.Args[0], "cannot use sorted with %s", argTypes[0])
+		}
+	}
+}
+
+// shellArgs checks the arguments of a shell builtin, the script followed by
+// the variables to pass to it, and turns the variables into ShellVar nodes
+func shellArgs(posh *types.PoshFile, call *FunctionCall) {
+	if len(call.Args) < 2 {
+		posh.Errorf(call.Callable, "%s needs a script to run", call.Callable.GetImage())
+		return
+	}
+
+	for i, arg := range call.Args[2:] {
+		identifier := bareIdentifier(arg)
+		if identifier == nil {
+			posh.Errorf(call.Callable, "the arguments of %s after the script must be variables", call.Callable.GetImage())
+			continue
+		}
+
+		call.Args[i+2] = &ShellVar{
+			BaseNode: types.BaseNode{
+				Type: "SHELL_VAR",
+			},
+			Identifier: identifier,
+		}
+	}
+}
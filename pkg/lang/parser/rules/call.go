@@ -17,37 +17,193 @@ type Flag struct {
 
 func (n *Flag) ToGoAst() ast.Node {
 	// treat the flag as a string
-	flagStr := strings.Repeat("-", n.DashCount) + n.Identifier.GetImage()
+	flagStr := strings.Repeat("-", n.DashCount)
+	if n.Identifier != nil {
+		flagStr += n.Identifier.GetImage()
+	}
+
 	return &ast.BasicLit{
 		Kind:  token.STRING,
 		Value: fmt.Sprintf(`"%s"`, flagStr),
 	}
 }
 
+type NamedArg struct {
+	types.BaseNode
+	Name  types.Node `json:"name"`
+	Value types.Node `json:"value"`
+}
+
+func (n *NamedArg) GetPos() *types.Pos {
+	return n.Name.GetPos()
+}
+
+func (n *NamedArg) StaticAnalysis(posh *types.PoshFile) {
+	n.Value.StaticAnalysis(posh)
+}
+
+// Spread passes the items of a list as separate arguments: git(...args)
+type Spread struct {
+	types.BaseNode
+	Op    types.Node `json:"op"`
+	Value types.Node `json:"value"`
+}
+
+func (n *Spread) GetPos() *types.Pos {
+	return n.Op.GetPos()
+}
+
+func (n *Spread) StaticAnalysis(posh *types.PoshFile) {
+	n.Value.StaticAnalysis(posh)
+
+	if valueType := typeOf(posh, n.Value); valueType != "" && elemType(valueType) == "" {
+		posh.Errorf(n, "cannot spread %s, only lists can be spread", valueType)
+	}
+}
+
+func (n *Spread) ToGoAst() ast.Node {
+	return valueToGoAst(n.Value)
+}
+
+func isSpread(node types.Node) bool {
+	_, ok := node.(*Spread)
+	return ok
+}
+
+// Options are the named arguments of block statements such as sandbox
+type Options struct {
+	types.BaseNode
+	Options []*NamedArg `json:"options"`
+}
+
 type FunctionCall struct {
 	types.BaseNode
 	Callable types.Node   `json:"callable"`
 	Args     []types.Node `json:"args"`
+	Builtin  *Builtin     `json:"builtin"`
+	// InPipe is set for the calls that are stages of a pipe
+	InPipe bool `json:"inPipe"`
+	// IsCommand is set for external commands and shell builtins
+	IsCommand bool `json:"isCommand"`
+	// SpreadArgs is set for the commands that take lists as arguments, see
+	// exec.Args
+	SpreadArgs bool `json:"spreadArgs"`
+	// Receiver is the value of a call with the method call syntax, it is
+	// passed as the first argument: name.upper()
+	Receiver types.Node `json:"receiver"`
+	// Method is set for the calls of the methods of the standard library
+	Method *Method `json:"method"`
 }
 
-func (n *FunctionCall) ToGoAst() ast.Node {
+func (n *FunctionCall) GetPos() *types.Pos {
+	return n.Callable.GetPos()
+}
+
+// isStandaloneCommand tells if the call runs a command outside of a pipe
+func (n *FunctionCall) isStandaloneCommand() bool {
+	return n.IsCommand && !n.InPipe
+}
+
+func (n *FunctionCall) callToGoAst() ast.Expr {
 	args := []ast.Expr{}
 	for _, arg := range n.Args {
 		args = append(args, arg.ToGoAst().(ast.Expr))
 	}
 
-	return &ast.CallExpr{
-		Fun:  n.Callable.ToGoAst().(ast.Expr),
+	fun := n.Callable.ToGoAst().(ast.Expr)
+	if n.Builtin != nil {
+		fun = n.Builtin.ToGoAst().(ast.Expr)
+	}
+
+	// rm(ctx, "-f", files) becomes rm(ctx, exec.Args("-f", files)...)
+	if n.SpreadArgs {
+		return &ast.CallExpr{
+			Fun: fun,
+			Args: []ast.Expr{
+				args[0],
+				&ast.CallExpr{
+					Fun: &ast.SelectorExpr{
+						X:   &ast.Ident{Name: "exec"},
+						Sel: &ast.Ident{Name: "Args"},
+					},
+					Args: args[1:],
+				},
+			},
+			Ellipsis: 1,
+		}
+	}
+
+	call := &ast.CallExpr{
+		Fun:  fun,
 		Args: args,
 	}
+
+	// a spread list is the last argument: git("log", args...)
+	if len(n.Args) > 0 {
+		if isSpread(n.Args[len(n.Args)-1]) {
+			call.Ellipsis = 1
+		}
+	}
+
+	return call
+}
+
+func (n *FunctionCall) ToGoAst() ast.Node {
+	// a command outside of a pipe evaluates to its output
+	if n.isStandaloneCommand() {
+		return &ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X: &ast.CallExpr{
+					Fun: &ast.SelectorExpr{
+						X:   n.callToGoAst(),
+						Sel: &ast.Ident{Name: "Wait"},
+					},
+				},
+				Sel: &ast.Ident{Name: "ToString"},
+			},
+		}
+	}
+
+	return n.callToGoAst()
 }
 
 func (n *FunctionCall) StaticAnalysis(posh *types.PoshFile) {
+	if n.methodAnalysis(posh) {
+		return
+	}
+
 	n.Callable.StaticAnalysis(posh)
 
 	if n.Callable.GetType() == "IDENTIFIER" {
 		image := n.Callable.GetImage()
-		if _, ok := posh.Environment.Get(image); !ok {
+		_, inScope := posh.Environment.Get(image)
+		builtin, isBuiltin := Builtins[image]
+
+		// functions and variables in scope shadow builtins and commands
+		if !inScope && isBuiltin {
+			n.Builtin = &builtin
+			n.IsCommand = builtin.Shell
+			if builtin.Package != "" {
+				posh.StdImports[builtin.Package] = true
+			}
+		} else if !inScope {
+			n.IsCommand = true
+			posh.StdImports["exec"] = true
+		}
+
+		// function values imported from other modules are called through
+		// their package: lib.Handler(x)
+		if pkg := importedPackage(posh, image); pkg != "" {
+			n.Callable = &Numeric{
+				BaseNode: types.BaseNode{
+					Type: "NUMERIC",
+				},
+				Value:   n.Callable,
+				Package: pkg,
+			}
+		}
+
+		if !inScope && !isBuiltin && !hasTopLevelAssignment(posh, image) {
 			// We need to add {identifier} := exec.ExternalCommand("{identifier}")
 			posh.TopLevelAssignments = append(posh.TopLevelAssignments, &ast.ValueSpec{
 				Names: []*ast.Ident{{Name: image}},
@@ -69,12 +225,269 @@ func (n *FunctionCall) StaticAnalysis(posh *types.PoshFile) {
 		}
 	}
 
+	// commands outside of pipes need a RunContext too
+	if n.isStandaloneCommand() {
+		n.Args = append([]types.Node{&RunContext{}}, n.Args...)
+	}
+
+	// shell builtins, and any command when it is a heredoc, read the source of
+	// a pipe from stdin instead of taking it as an argument
+	if n.IsCommand && n.InPipe && len(n.Args) > 1 {
+		ctx, ok := n.Args[0].(*RunContext)
+		_, isHeredoc := n.Args[1].(*Heredoc)
+
+		if ok && (isHeredoc || n.Builtin != nil && n.Builtin.Shell) {
+			ctx.Stdin = n.Args[1]
+			n.Args = append(n.Args[:1], n.Args[2:]...)
+		}
+	}
+
+	if n.Builtin != nil && n.Builtin.Shell {
+		shellArgs(posh, n)
+	}
+
+	// arrow functions take their types from the params, see bindArgs, and
+	// the receiver of a method is already analyzed
 	for _, arg := range n.Args {
+		if !isArrowFunction(arg) && arg != n.Receiver {
+			arg.StaticAnalysis(posh)
+		}
+	}
+
+	// complete() reads the command before it like a command does
+	if n.InPipe && (n.IsCommand || n.Builtin != nil && n.Builtin.Name == "Complete") {
+		n.commandSource()
+	}
+
+	if n.Builtin != nil && !n.Builtin.Shell {
+		builtinArgs(posh, n)
+	}
+
+	if signature, ok := signatureOf(posh, n.Callable); ok {
+		bindArgs(posh, n, signature)
+	} else {
+		for _, arg := range n.Args {
+			if named, ok := arg.(*NamedArg); ok {
+				posh.Errorf(named, "named arguments can only be passed to functions declared with fn")
+			} else if isArrowFunction(arg) {
+				arg.StaticAnalysis(posh)
+			}
+		}
+	}
+
+	// lists are passed to commands as one argument per item
+	if n.IsCommand && n.Builtin == nil {
+		for _, arg := range n.Args[1:] {
+			if isSpread(arg) || elemType(typeOf(posh, arg)) != "" {
+				n.SpreadArgs = true
+			}
+		}
+	}
+}
+
+// commandSource streams the output of a command that is the source of a pipe
+// into the command that reads it, instead of passing its output as an
+// argument: ls("/") | wc(-l) is wc(ls(ctx, "/"), "-l")
+func (n *FunctionCall) commandSource() {
+	ctx, ok := n.Args[0].(*RunContext)
+	if !ok {
+		return
+	}
+
+	src, stdin := ctx.Stdin, true
+	if src == nil {
+		src, stdin = n.Args[1], false
+	}
+
+	call, ok := src.(*FunctionCall)
+	if !ok || !call.isStandaloneCommand() {
+		return
+	}
+
+	call.InPipe = true
+	if stdin {
+		n.Args[0] = call
+	} else {
+		n.Args = n.Args[1:]
+	}
+}
+
+// signatureOf returns the signature of a function declared with fn that is
+// called by name or through a module: deploy(...) or lib.Deploy(...)
+func signatureOf(posh *types.PoshFile, callable types.Node) (types.Export, bool) {
+	switch n := callable.(type) {
+	case *types.TokenNode:
+		signature, ok := posh.Functions[n.Image]
+		// a variable in scope shadows the function
+		if valueType, _ := posh.Environment.Get(n.Image); !ok || valueType != funcType(signature) {
+			return types.Export{}, false
+		}
+		return signature, true
+	case *DotNotation:
+		if n.IsValue || n.Enum != "" || len(n.Accessors) != 2 {
+			break
+		}
+
+		valueType, _ := posh.Environment.Get(n.Accessors[0].GetImage())
+		if !strings.HasPrefix(valueType, "module:") {
+			break
+		}
+
+		signature, ok := posh.CompiledFiles[strings.TrimPrefix(valueType, "module:")].Exports[n.Accessors[1].GetImage()]
+		signature.Package = n.Accessors[0].GetImage()
+		return signature, ok && signature.IsFunc
+	}
+
+	return types.Export{}, false
+}
+
+// bindArgs puts the arguments of a call in the order of the params of the
+// function, named arguments go to the params with their names and the params
+// that are left out get their default values: deploy(env="prod")
+func bindArgs(posh *types.PoshFile, n *FunctionCall, signature types.Export) {
+	// the results of a function can be passed on as a whole: f(g())
+	if len(n.Args) == 1 && len(tupleTypes(typeOf(posh, n.Args[0]))) > 1 {
+		return
+	}
+
+	name := n.Callable.GetImage()
+	if dot, ok := n.Callable.(*DotNotation); ok {
+		name = dot.Accessors[0].GetImage() + "." + dot.Accessors[1].GetImage()
+	}
+
+	// the items of a variadic param come after the other params
+	params := signature.Params
+	variadic := len(params) > 0 && params[len(params)-1].Variadic
+	if variadic {
+		params = params[:len(params)-1]
+	}
+
+	args := make([]types.Node, len(params))
+	rest := []types.Node{}
+	hasNamed := false
+
+	for i, arg := range n.Args {
+		named, ok := arg.(*NamedArg)
+		if !ok {
+			if hasNamed {
+				posh.Errorf(arg, "positional arguments must come before named arguments")
+				return
+			}
+
+			if i < len(args) {
+				args[i] = arg
+			} else if variadic {
+				rest = append(rest, arg)
+			} else {
+				posh.Errorf(arg, "too many arguments, %s takes %d", name, len(args))
+				return
+			}
+
+			continue
+		}
+
+		hasNamed = true
+		index := -1
+		for j, param := range params {
+			if param.Name == named.Name.GetImage() {
+				index = j
+			}
+		}
+
+		if variadic && named.Name.GetImage() == signature.Params[len(params)].Name {
+			posh.Errorf(named, "%s is variadic, it can not be passed by name", named.Name.GetImage())
+		} else if index < 0 {
+			posh.Errorf(named, "%s has no parameter named %s", name, named.Name.GetImage())
+		} else if args[index] != nil {
+			posh.Errorf(named, "argument %s is passed more than once", named.Name.GetImage())
+		} else {
+			args[index] = named.Value
+		}
+	}
+
+	// Go only spreads a list into a variadic param that takes nothing else
+	for i, arg := range append(args, rest...) {
+		if isSpread(arg) && (i < len(args) || len(rest) > 1) {
+			posh.Errorf(arg, "a spread list must be the only argument of the variadic parameter of %s", name)
+			return
+		}
+	}
+
+	if variadic {
+		param := signature.Params[len(params)]
+		for _, arg := range rest {
+			if isSpread(arg) {
+				checkType(posh, []types.Node{arg}, "[]"+param.Type, "argument of type")
+			} else {
+				analyzeArrowFunction(posh, arg, param.Type)
+				checkType(posh, []types.Node{arg}, param.Type, "argument of type")
+			}
+		}
+	}
+
+	for i, param := range params {
+		if args[i] != nil {
+			analyzeArrowFunction(posh, args[i], param.Type)
+			checkType(posh, args[i:i+1], param.Type, "argument of type")
+		} else if param.Default != nil {
+			args[i] = defaultValue(param.Default, signature.Package)
+		} else {
+			posh.Errorf(n.Callable, "missing argument %s in call to %s", param.Name, name)
+			return
+		}
+	}
+
+	n.Args = append(args, rest...)
+}
+
+// defaultValue returns the default value of a param for a call, the enum
+// values of the functions of other modules are qualified with their package
+func defaultValue(value types.Node, pkg string) types.Node {
+	dot, ok := value.(*DotNotation)
+	if !ok || dot.Enum == "" || pkg == "" {
+		return value
+	}
+
+	qualified := *dot
+	qualified.Package = pkg
+	return &qualified
+}
+
+// analyzeArrowFunction analyzes an argument that is an arrow function once
+// the type of its param is known
+func analyzeArrowFunction(posh *types.PoshFile, arg types.Node, paramType string) {
+	if isArrowFunction(arg) {
+		expectType(arg, paramType)
 		arg.StaticAnalysis(posh)
 	}
 }
 
+func hasTopLevelAssignment(posh *types.PoshFile, name string) bool {
+	for _, spec := range posh.TopLevelAssignments {
+		for _, ident := range spec.(*ast.ValueSpec).Names {
+			if ident.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func (n *FunctionCall) ToGoStatementAst() ast.Stmt {
+	// a command outside of a pipe streams its output: exec.Run(cmd(ctx, ...))
+	if n.isStandaloneCommand() {
+		return &ast.ExprStmt{
+			X: &ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "exec"},
+					Sel: &ast.Ident{Name: "Run"},
+				},
+				Args: []ast.Expr{n.callToGoAst()},
+			},
+		}
+	}
+
 	return &ast.ExprStmt{
 		X: n.ToGoAst().(ast.Expr),
 	}
@@ -84,14 +497,8 @@ func MatchFlag(nodes []types.Node, offset int) types.Result {
 	start := offset
 
 	// a flag is an identifier prefixed with one or two dashes
-	// --identifier or -identifier
-
-	// check for the first dash
-	if nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != "-" {
-		return types.Result{FailedAt: &nodes[offset]}
-	}
-
-	offset++
+	// --identifier or -identifier, or just the dashes: - is commonly used
+	// for stdin and -- for the end of the flags
 
 	node := Flag{
 		BaseNode: types.BaseNode{
@@ -100,20 +507,131 @@ func MatchFlag(nodes []types.Node, offset int) types.Result {
 		DashCount: 1,
 	}
 
-	// check for the second dash
-	if nodes[offset].GetType() == "PUNCTUATOR" && nodes[offset].GetImage() == "-" {
+	// -- is a single token, it is also the decrement operator
+	if isPunctuator(nodes[offset], "--") {
 		node.DashCount = 2
-		offset++
+	} else if !isPunctuator(nodes[offset], "-") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	if isPunctuator(nodes[offset], ",") || isPunctuator(nodes[offset], ")") {
+		return types.Result{Node: &node, Start: start, End: offset}
 	}
 
 	if nodes[offset].GetType() != "IDENTIFIER" {
 		return types.Result{FailedAt: &nodes[offset]}
 	}
 
+	// a flag is a whole argument, -x * 2 is a negation
+	if !isPunctuator(nodes[offset+1], ",") && !isPunctuator(nodes[offset+1], ")") {
+		return types.Result{FailedAt: &nodes[offset+1]}
+	}
+
 	node.Identifier = nodes[offset]
 	return types.Result{Node: &node, Start: start, End: offset + 1}
 }
 
+func MatchSpread(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// ... EXPRESSION
+
+	if !isPunctuator(nodes[offset], "...") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	res := MatchExpr(nodes, offset+1)
+	if res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	node := Spread{
+		BaseNode: types.BaseNode{
+			Type: "SPREAD",
+		},
+		Op:    nodes[offset],
+		Value: res.Node,
+	}
+
+	return types.Result{Node: &node, Start: start, End: res.End}
+}
+
+func MatchNamedArg(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// IDENTIFIER = EXPRESSION
+
+	if nodes[offset].GetType() != "IDENTIFIER" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := NamedArg{
+		BaseNode: types.BaseNode{
+			Type: "NAMED_ARG",
+		},
+		Name: nodes[offset],
+	}
+
+	offset++
+
+	if nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != "=" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	if res := MatchExpr(nodes, offset); res.End > res.Start {
+		node.Value = res.Node
+		offset = res.End
+	} else {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset}
+}
+
+func MatchOptions(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// ( (NAMED_ARG (, NAMED_ARG)*)? )
+
+	if nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != "(" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	node := Options{
+		BaseNode: types.BaseNode{
+			Type: "OPTIONS",
+		},
+	}
+
+	for {
+		if nodes[offset].GetType() == "PUNCTUATOR" && nodes[offset].GetImage() == ")" {
+			break
+		}
+
+		if res := MatchNamedArg(nodes, offset); res.End > res.Start {
+			node.Options = append(node.Options, res.Node.(*NamedArg))
+			offset = res.End
+		} else {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		if nodes[offset].GetType() == "PUNCTUATOR" && nodes[offset].GetImage() == "," {
+			offset++
+		}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
+
 func MatchFunctionCall(nodes []types.Node, offset int) types.Result {
 	start := offset
 
@@ -140,6 +658,19 @@ func MatchFunctionCall(nodes []types.Node, offset int) types.Result {
 		Callable: callable,
 	}
 
+	res := matchArgs(nodes, offset, &node)
+	if res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	return types.Result{Node: &node, Start: start, End: res.End}
+}
+
+// matchArgs matches the arguments of a call and adds them to the call
+func matchArgs(nodes []types.Node, offset int, node *FunctionCall) types.Result {
+	start := offset
+	defer allowLiterals()()
+
 	if nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != "(" {
 		return types.Result{FailedAt: &nodes[offset]}
 	}
@@ -151,15 +682,23 @@ func MatchFunctionCall(nodes []types.Node, offset int) types.Result {
 			break
 		}
 
-		// Look for simple expressions or flags
-		if res := MatchSimpleExpression(nodes, offset); res.End > res.Start {
+		// Look for spread lists, named arguments, flags or expressions, an
+		// argument that is a dash followed by a name is a flag rather than a
+		// negation: ls(-l)
+		if res := MatchSpread(nodes, offset); res.End > res.Start {
+			node.Args = append(node.Args, res.Node)
+			offset = res.End
+		} else if res := MatchNamedArg(nodes, offset); res.End > res.Start {
 			node.Args = append(node.Args, res.Node)
 			offset = res.End
 		} else if res := MatchFlag(nodes, offset); res.End > res.Start {
 			node.Args = append(node.Args, res.Node)
 			offset = res.End
+		} else if res := MatchExpr(nodes, offset); res.End > res.Start {
+			node.Args = append(node.Args, res.Node)
+			offset = res.End
 		} else {
-			return types.Result{FailedAt: &nodes[offset]}
+			return types.Result{FailedAt: res.FailedAt}
 		}
 
 		if nodes[offset].GetType() == "PUNCTUATOR" && nodes[offset].GetImage() == "," {
@@ -167,5 +706,5 @@ func MatchFunctionCall(nodes []types.Node, offset int) types.Result {
 		}
 	}
 
-	return types.Result{Node: &node, Start: start, End: offset + 1}
+	return types.Result{Node: node, Start: start, End: offset + 1}
 }
@@ -0,0 +1,188 @@
+package rules
+
+import "testing"
+
+const splitFunc = `fn split(address string) (string, int) {
+  if address == "" {
+    return "localhost", 80
+  }
+  return address, 8080
+}
+`
+
+func TestMultipleReturnValues(t *testing.T) {
+	expectGo(t, []string{splitFunc + `
+fn main() {
+  host, port = split("")
+  other, port = split("x")
+  m = {"a": 1}
+  value, ok = m["a"]
+  a, b = 1, "s"
+  a, b = a + 1, b
+  io.Println(host, port, other, value, ok, a, b, split("y"))
+}`},
+		"func split(address string) (string, int) {",
+		`return "localhost", 80`,
+		`host, port := split("")`,
+		// port exists, so only other is declared
+		"var other string",
+		`other, port = split("x")`,
+		`value, ok := m["a"]`,
+		`a, b := 1, "s"`,
+		"a, b = a+1, b",
+		`io.Println(host, port, other, value, ok, a, b, split("y"))`,
+	)
+}
+
+func TestMultipleReturnValueErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"count of names", splitFunc + "fn main() {\n  a, b, c = split(\"\")\n}", "assignment mismatch: 3 names but 2 values"},
+		{"one name", splitFunc + "fn main() {\n  a = split(\"\")\n}", "cannot assign 2 values to a name, use as many names: a, b = ..."},
+		{"types", splitFunc + "fn main() {\n  port = 1\n  port, host = split(\"\")\n}", "cannot assign string to port of type int"},
+		{"name twice", splitFunc + "fn main() {\n  a, a = split(\"\")\n}", "a is assigned more than once"},
+		{"count of values", "fn split() (string, int) {\n  return \"a\"\n}", "the function returns 2 values, found 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{test.code}, test.error)
+		})
+	}
+}
+
+const deployFunc = `fn deploy(env string, dryRun bool = false, replicas int = 2) string {
+  return "${env} ${dryRun} ${replicas}"
+}
+`
+
+func TestDefaultsAndNamedArguments(t *testing.T) {
+	expectGo(t, []string{deployFunc + `
+fn main() {
+  io.Println(deploy("prod"))
+  io.Println(deploy(env="prod", dryRun=true))
+  io.Println(deploy("dev", replicas=-3))
+  io.Println(deploy(replicas=1, env="qa"))
+}`},
+		`io.Println(deploy("prod", false, 2))`,
+		`io.Println(deploy("prod", true, 2))`,
+		`io.Println(deploy("dev", false, -3))`,
+		`io.Println(deploy("qa", false, 1))`,
+	)
+}
+
+func TestMainDefaults(t *testing.T) {
+	expectGo(t, []string{`enum Env { dev, prod }
+
+fn main(port int = 8080, name string = "posh", env Env = Env.prod, ratio float64 = 0.5) {
+  io.Println(port, name, env, ratio)
+}`},
+		`flag.IntVar(&port, "port", 8080, "")`,
+		`flag.StringVar(&name, "name", "posh", "")`,
+		`flag.Float64Var(&ratio, "ratio", 0.5, "")`,
+	)
+}
+
+func TestImportedDefaults(t *testing.T) {
+	module := `enum Env { dev, prod }
+
+fn Deploy(name string, env Env = Env.prod, dryRun bool = false) string {
+  return "${name} ${env} ${dryRun}"
+}`
+
+	// the enum values of another module are qualified with its package
+	expectGo(t, []string{`from "/mod1.posh" import Deploy
+
+fn main() {
+  io.Println(Deploy("c"))
+}`, module},
+		`io.Println(Deploy("c", mod1.EnvProd, false))`,
+	)
+
+	expectGo(t, []string{`from "/mod1.posh" import * as mod1
+
+fn main() {
+  io.Println(mod1.Deploy("c", dryRun=true))
+}`, module},
+		`io.Println(mod1.Deploy("c", mod1.EnvProd, true))`,
+	)
+}
+
+func TestCallErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"missing argument", "fn main() {\n  deploy()\n}", "missing argument env in call to deploy"},
+		{"passed twice", "fn main() {\n  deploy(env=\"a\", env=\"b\")\n}", "argument env is passed more than once"},
+		{"unknown name", "fn main() {\n  deploy(foo=1, env=\"a\")\n}", "deploy has no parameter named foo"},
+		{"positional after named", "fn main() {\n  deploy(env=\"a\", true)\n}", "positional arguments must come before named arguments"},
+		{"too many", "fn main() {\n  deploy(\"a\", true, 1, 2)\n}", "too many arguments, deploy takes 3"},
+		{"argument type", "fn main() {\n  deploy(1)\n}", "argument of type string"},
+		{"named to go function", "fn main() {\n  io.Println(x=1)\n}", "named arguments can only be passed to functions declared with fn"},
+		{"default type", "fn f(x int = \"a\") void {\n}", "default value of type int"},
+		{"default not constant", "fn f(x int = len(\"ab\")) void {\n}", "the default value of x must be a literal or an enum value"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{deployFunc + test.code}, test.error)
+		})
+	}
+}
+
+const sumFunc = `fn sum(base int, xs ...int) int {
+  total = base
+  for x in xs {
+    total += x
+  }
+  return total
+}
+`
+
+func TestVariadicParams(t *testing.T) {
+	expectGo(t, []string{sumFunc + `
+fn gitLog(args ...string) string {
+  return echo("log", "--oneline", ...args)
+}
+
+fn main(verbose bool, files ...string) {
+  io.Println(sum(1), sum(1, 2, 3), sum(base=5))
+  nums = [4, 5]
+  io.Println(sum(0, ...nums), append(nums, ...nums))
+  echo("a", ...files, "b")
+}`},
+		"func sum(base int, xs ...int) int {",
+		`return echo(&exec.RunContext{}, exec.Args("log", "--oneline", args)...).Wait().ToString()`,
+		"files := flag.Args()",
+		"io.Println(sum(1), sum(1, 2, 3), sum(5))",
+		"io.Println(sum(0, nums...), append(nums, nums...))",
+		`exec.Run(echo(&exec.RunContext{}, exec.Args("a", files, "b")...))`,
+	)
+}
+
+func TestVariadicErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"not last", "fn f(xs ...int, base int) void {\n}", "only the last parameter can be variadic"},
+		{"default", "fn f(xs ...int = 1) void {\n}", "a variadic parameter can not have a default value"},
+		{"main", "fn main(files ...int) {\n}", "the variadic parameter of main takes the arguments after the flags, it must be ...string"},
+		{"spread with items", "fn main() {\n  nums = [1]\n  sum(1, 2, ...nums)\n}", "a spread list must be the only argument of the variadic parameter of sum"},
+		{"spread before", "fn main() {\n  nums = [1]\n  sum(1, ...nums, 2)\n}", "a spread list must be the only argument of the variadic parameter of sum"},
+		{"by name", "fn main() {\n  nums = [1]\n  sum(1, xs=nums)\n}", "xs is variadic, it can not be passed by name"},
+		{"not a list", "fn main() {\n  io.Println(...3)\n}", "cannot spread int, only lists can be spread"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{sumFunc + test.code}, test.error)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package rules
 
 import (
+	"fmt"
 	"go/ast"
 	"go/token"
 
@@ -23,150 +24,163 @@ var cmpTokenMap = map[string]token.Token{
 	"!=": token.NEQ,
 }
 
-func (a *ComparisonNode) ToGoAst() ast.Node {
-	var lhs, rhs ast.Expr
+func (a *ComparisonNode) GetPos() *types.Pos {
+	return a.Lhs.GetPos()
+}
 
-	if a.Lhs != nil {
-		lhs = a.Lhs.ToGoAst().(ast.Expr)
-	}
+func (a *ComparisonNode) StaticAnalysis(posh *types.PoshFile) {
+	a.Lhs.StaticAnalysis(posh)
+	a.Rhs.StaticAnalysis(posh)
+}
 
-	if a.Rhs != nil {
-		rhs = a.Rhs.ToGoAst().(ast.Expr)
-	}
+func (a *ComparisonNode) ToGoAst() ast.Node {
+	return binaryExpr(valueToGoAst(a.Lhs), cmpTokenMap[a.Op.GetImage()], valueToGoAst(a.Rhs))
+}
 
-	op := cmpTokenMap[a.Op.GetImage()]
+// ComparisonChain is a chain of comparisons: a < b <= c, which is equivalent
+// to (a < b) and (b <= c) except that b is evaluated only once
+type ComparisonChain struct {
+	types.BaseNode
+	Operands []types.Node `json:"operands"`
+	Ops      []types.Node `json:"ops"`
+}
 
-	return &ast.BinaryExpr{
-		X:  lhs,
-		Op: op,
-		Y:  rhs,
-	}
+func (n *ComparisonChain) GetPos() *types.Pos {
+	return n.Operands[0].GetPos()
 }
 
-func isCmpOperator(value string) bool {
-	return value == ">" || value == "<"
+func (n *ComparisonChain) StaticAnalysis(posh *types.PoshFile) {
+	for _, operand := range n.Operands {
+		operand.StaticAnalysis(posh)
+	}
 }
 
-func MatchComparison(nodes []types.Node, offset int) types.Result {
-	start := offset
+// isPure tells if evaluating an expression more than once has no effects
+// and always gives the same value
+func isPure(node types.Node) bool {
+	switch n := node.(type) {
+	case *Numeric, *SimpleExpression, *Boolean, *DotNotation, *types.TokenNode:
+		return true
+	case *Parens:
+		return isPure(n.Value)
+	case *Unary:
+		return isPure(n.Value)
+	case *Negation:
+		return isPure(n.Value)
+	case *ArithmeticNode:
+		return isPure(n.Lhs) && isPure(n.Rhs)
+	case *ComparisonNode:
+		return isPure(n.Lhs) && isPure(n.Rhs)
+	case *Logical:
+		return isPure(n.Lhs) && isPure(n.Rhs)
+	}
 
-	// We should recursively match the following:
-	// NUMERIC (COMPARISON_OPERATOR NUMERIC)*
-	// In PoSH, a < b < c is valid and is equivalent to (a < b) && (b < c)
+	return false
+}
 
-	var res types.Result
-	if res = MatchNumeric(nodes, offset); res.End <= res.Start {
-		return types.Result{FailedAt: &nodes[offset]}
+func (n *ComparisonChain) ToGoAst() ast.Node {
+	operands := make([]ast.Expr, len(n.Operands))
+	for i, operand := range n.Operands {
+		operands[i] = valueToGoAst(operand)
 	}
-	offset = res.End
 
-	cmp := ComparisonNode{
-		BaseNode: types.BaseNode{
-			Type: "COMPARISON",
-		},
-		Lhs: res.Node,
+	pure := true
+	for _, operand := range n.Operands[1 : len(n.Operands)-1] {
+		pure = pure && isPure(operand)
 	}
 
-	cmpList := []ComparisonNode{}
-
-	for offset < len(nodes) {
-		if nodes[offset].GetType() != "PUNCTUATOR" {
-			break
+	// a < b < c is a < b && b < c when b can be evaluated twice
+	if pure {
+		var chain ast.Expr
+		for i, op := range n.Ops {
+			cmp := binaryExpr(operands[i], cmpTokenMap[op.GetImage()], operands[i+1])
+			if chain == nil {
+				chain = cmp
+			} else {
+				chain = binaryExpr(chain, token.LAND, cmp)
+			}
 		}
+		return chain
+	}
 
-		// We should check if the operator is a comparison operator
-		// Some operators are two characters long, so we should check for that
-		image := nodes[offset].GetImage()
-		deltaOffset := 1
-		if image == "!" {
-			if nodes[offset+1].GetImage() != "=" {
-				return types.Result{FailedAt: &nodes[offset]}
-			}
-			deltaOffset = 2
-			image = "!="
-		} else if image == "=" {
-			if nodes[offset+1].GetImage() != "=" {
-				return types.Result{FailedAt: &nodes[offset]}
+	// Otherwise the operands are evaluated once, from left to right, and the
+	// chain stops at the first false comparison:
+	//
+	//	func() bool {
+	//		__cmp1 := f()
+	//		if !(a < __cmp1) {
+	//			return false
+	//		}
+	//		return __cmp1 < c
+	//	}()
+	stmts := []ast.Stmt{}
+	for i, op := range n.Ops {
+		// all operands but the last one are stored before they are compared
+		for j := i; j <= i+1 && j < len(operands)-1; j++ {
+			if isPure(n.Operands[j]) {
+				continue
 			}
-			deltaOffset = 2
-			image = "=="
-		} else if isCmpOperator(image) {
-			// check for = (e.g. <=, >=)
-			if nodes[offset+1].GetImage() == "=" {
-				deltaOffset = 2
-				image += "="
+
+			if _, ok := operands[j].(*ast.Ident); ok {
+				continue
 			}
-		} else if len(cmpList) > 0 {
-			// If we have already matched a comparison, we should stop here
-			break
-		} else {
-			return types.Result{FailedAt: &nodes[offset]}
-		}
 
-		cmp.Op = &types.TokenNode{
-			BaseNode: types.BaseNode{
-				Type: "COMPARISON_OPERATOR",
-			},
-			Image: image,
-			Pos:   nodes[offset].GetPos(),
+			temp := ast.NewIdent(fmt.Sprintf("__cmp%d", j))
+			stmts = append(stmts, &ast.AssignStmt{
+				Lhs: []ast.Expr{temp},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{operands[j]},
+			})
+			operands[j] = temp
 		}
 
-		offset += deltaOffset
+		cmp := binaryExpr(operands[i], cmpTokenMap[op.GetImage()], operands[i+1])
 
-		// Now we should match the right hand side of the comparison
-		if res = MatchNumeric(nodes, offset); res.End <= res.Start {
-			return types.Result{FailedAt: &nodes[offset]}
+		if i == len(n.Ops)-1 {
+			stmts = append(stmts, &ast.ReturnStmt{Results: []ast.Expr{cmp}})
+			break
 		}
-		offset = res.End
-		cmp.Rhs = res.Node
 
-		cmpList = append(cmpList, cmp)
-		cmp = ComparisonNode{
-			BaseNode: types.BaseNode{
-				Type: "COMPARISON",
+		stmts = append(stmts, &ast.IfStmt{
+			Cond: &ast.UnaryExpr{Op: token.NOT, X: &ast.ParenExpr{X: cmp}},
+			Body: &ast.BlockStmt{
+				List: []ast.Stmt{
+					&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("false")}},
+				},
 			},
-			Lhs: res.Node,
-		}
+		})
 	}
 
-	if len(cmpList) == 0 {
-		return types.Result{FailedAt: &nodes[offset]}
-	} else if len(cmpList) == 1 {
-		return types.Result{Node: &cmpList[0], Start: start, End: offset}
-	}
-
-	// a < b < c should be equivalent to (a < b) && (b < c)
-	// We should build a tree of AND nodes
-
-	logicNode := Logical{
-		BaseNode: types.BaseNode{
-			Type: "LOGICAL",
-		},
-		Op: &types.TokenNode{
-			BaseNode: types.BaseNode{
-				Type: "LOGICAL_OPERATOR",
+	return &ast.CallExpr{
+		Fun: &ast.FuncLit{
+			Type: &ast.FuncType{
+				Params:  &ast.FieldList{},
+				Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("bool")}}},
 			},
-			Image: "and",
-			Pos:   cmpList[0].GetPos(),
+			Body: &ast.BlockStmt{List: stmts},
 		},
-		Lhs: &cmpList[0],
 	}
+}
 
-	for i := 1; i < len(cmpList); i++ {
-		logicNode.Rhs = &Logical{
+// comparisonChain returns the comparison of the operands with the operators
+// between them
+func comparisonChain(operands []types.Node, ops []types.Node) types.Node {
+	if len(ops) == 1 {
+		return &ComparisonNode{
 			BaseNode: types.BaseNode{
-				Type: "LOGICAL",
-			},
-			Op: &types.TokenNode{
-				BaseNode: types.BaseNode{
-					Type: "LOGICAL_OPERATOR",
-				},
-				Image: "and",
-				Pos:   cmpList[i].GetPos(),
+				Type: "COMPARISON",
 			},
-			Lhs: &cmpList[i],
+			Lhs: operands[0],
+			Op:  ops[0],
+			Rhs: operands[1],
 		}
 	}
 
-	return types.Result{Node: &logicNode, Start: start, End: offset}
+	return &ComparisonChain{
+		BaseNode: types.BaseNode{
+			Type: "COMPARISON_CHAIN",
+		},
+		Operands: operands,
+		Ops:      ops,
+	}
 }
@@ -0,0 +1,63 @@
+package rules
+
+import "testing"
+
+// chainProgram returns a program that assigns a chain of comparisons to x,
+// next is a function with a side effect
+func chainProgram(expr string) []string {
+	return []string{`fn next() int {
+  io.Println("next")
+  return 1
+}
+
+fn f(a int, b int, c int, i int, n int) void {
+  x = ` + expr + `
+  io.Println(x)
+}`}
+}
+
+func TestComparisonChain(t *testing.T) {
+	tests := []struct {
+		expr string
+		goIs []string
+	}{
+		{"a < b < c", []string{"x := a < b && b < c"}},
+		{"0 <= i < n", []string{"x := 0 <= i && i < n"}},
+		{"a < b <= c == n", []string{"x := a < b && b <= c && c == n"}},
+		{"a + 1 < b * 2 < c", []string{"x := a+1 < b*2 && b*2 < c"}},
+		{"a < b", []string{"x := a < b"}},
+		// the middle operand has a side effect, it is stored and evaluated
+		// once, after the operand on its left
+		{"a < next() < c", []string{
+			"x := func() bool {",
+			"__cmp1 := next()",
+			"if !(a < __cmp1) {",
+			"return false",
+			"return __cmp1 < c",
+			"}()",
+		}},
+		// every operand is evaluated once, from left to right, and the last
+		// one only when the comparisons before it hold
+		{"next() < next() < next()", []string{
+			"x := func() bool {",
+			"__cmp0 := next()",
+			"__cmp1 := next()",
+			"if !(__cmp0 < __cmp1) {",
+			"return false",
+			"return __cmp1 < next()",
+			"}()",
+		}},
+		{"0 <= next() < n < next()", []string{
+			"__cmp1 := next()",
+			"if !(0 <= __cmp1) {",
+			"if !(__cmp1 < n) {",
+			"return n < next()",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expectGo(t, chainProgram(test.expr), test.goIs...)
+		})
+	}
+}
@@ -49,11 +49,33 @@ func (n *IfStatement) ToGoStatementAst() ast.Stmt {
 	return &ifNode
 }
 
+func (n *IfStatement) StaticAnalysis(posh *types.PoshFile) {
+	n.Condition.StaticAnalysis(posh)
+	branchAnalysis(posh, n.Body)
+
+	for _, elif := range n.Elifs {
+		elif.Condition.StaticAnalysis(posh)
+		branchAnalysis(posh, elif.Body)
+	}
+
+	if n.Else.Body != nil {
+		branchAnalysis(posh, n.Else.Body)
+	}
+}
+
+// branchAnalysis analyzes the body of a branch in a scope of its own, like Go
+// the variables declared in it are not visible after it
+func branchAnalysis(posh *types.PoshFile, body types.Node) {
+	posh.Environment.PushScope()
+	body.StaticAnalysis(posh)
+	posh.Environment.PopScope()
+}
+
 func MatchIfStatement(nodes []types.Node, offset int) types.Result {
 	start := offset
 
 	// We are looking for the following:
-	// IF BOOLEAN BODY (ELSEIF BOOLEAN|LOGICAL BODY)* (ELSE BODY)?
+	// IF EXPRESSION BODY (ELIF EXPRESSION BODY)* (ELSE BODY)?
 
 	// try to match IF
 	if nodes[offset].GetType() != "KEYWORD" || nodes[offset].GetImage() != "if" {
@@ -68,15 +90,12 @@ func MatchIfStatement(nodes []types.Node, offset int) types.Result {
 		},
 	}
 
-	// try to match BOOLEAN or LOGICAL
-	if res := MatchLogical(nodes, offset); res.End > res.Start {
-		offset = res.End
-		node.Condition = res.Node
-	} else if res := MatchBoolean(nodes, offset); res.End > res.Start {
+	// try to match the condition
+	if res := matchHeader(nodes, offset); res.End > res.Start {
 		offset = res.End
 		node.Condition = res.Node
 	} else {
-		return types.Result{FailedAt: &nodes[offset]}
+		return types.Result{FailedAt: res.FailedAt}
 	}
 
 	// try to match BODY; we can reuse MatchFunctionBody here
@@ -87,7 +106,7 @@ func MatchIfStatement(nodes []types.Node, offset int) types.Result {
 		node.Body = res.Node
 	}
 
-	// try to match (ELSEIF BOOLEAN|LOGICAL BODY)*
+	// try to match (ELIF EXPRESSION BODY)*
 	// PoSH uses "elif" instead of "elseif" or "else if"
 	for {
 		if nodes[offset].GetType() != "KEYWORD" || nodes[offset].GetImage() != "elif" {
@@ -101,15 +120,12 @@ func MatchIfStatement(nodes []types.Node, offset int) types.Result {
 			},
 		}
 
-		// try to match BOOLEAN or LOGICAL
-		if res := MatchLogical(nodes, offset); res.End > res.Start {
-			offset = res.End
-			elifNode.Condition = res.Node
-		} else if res := MatchBoolean(nodes, offset); res.End > res.Start {
+		// try to match the condition
+		if res := matchHeader(nodes, offset); res.End > res.Start {
 			offset = res.End
 			elifNode.Condition = res.Node
 		} else {
-			return types.Result{FailedAt: &nodes[offset]}
+			return types.Result{FailedAt: res.FailedAt}
 		}
 
 		// try to match BODY
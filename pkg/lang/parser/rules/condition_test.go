@@ -0,0 +1,50 @@
+package rules
+
+import "testing"
+
+func TestBranchScopes(t *testing.T) {
+	// the variables declared in a branch are not visible after it
+	expectGo(t, []string{`fn main(v bool) {
+  if v {
+    y = 3
+    io.Println(y)
+  }
+  y = 4
+  var x = 1
+  if v {
+    var x = "s"
+    io.Println(x)
+  } elif not v {
+    x = 2
+    z = 1
+    io.Println(z)
+  } else {
+    z = "z"
+    io.Println(z)
+  }
+  io.Println(x, y)
+}`},
+		"if v {",
+		"y := 3",
+		"y := 4",
+		"x := 1",
+		"if v {",
+		`x := "s"`,
+		"} else if !v {",
+		"x = 2",
+		"z := 1",
+		"} else {",
+		`z := "z"`,
+		"io.Println(x, y)",
+	)
+}
+
+func TestBranchScopeErrors(t *testing.T) {
+	// the names of the enclosing scopes stay visible in the branches
+	expectError(t, []string{`fn main(v bool) {
+  const x = 1
+  if v {
+    x = 2
+  }
+}`}, "cannot assign to x, it is a const declared at 2:9")
+}
@@ -0,0 +1,158 @@
+package rules
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// Enum is a type with a fixed set of values: enum Env { dev, staging, prod },
+// its values are strings in Go
+type Enum struct {
+	types.BaseNode
+	Keyword    types.Node   `json:"keyword"`
+	Identifier types.Node   `json:"identifier"`
+	Values     []types.Node `json:"values"`
+}
+
+func (n *Enum) GetPos() *types.Pos {
+	return n.Keyword.GetPos()
+}
+
+// export returns the values of the enum
+func (n *Enum) export() types.Export {
+	export := types.Export{
+		Type:   n.Identifier.GetImage(),
+		IsType: true,
+		Values: []string{},
+	}
+
+	for _, value := range n.Values {
+		export.Values = append(export.Values, value.GetImage())
+	}
+
+	return export
+}
+
+// enumConst returns the Go name of a value of an enum: Env.us-east is EnvUsEast
+func enumConst(enum string, value string) string {
+	name := enum
+	for _, part := range strings.Split(value, "-") {
+		if part != "" {
+			name += exportedName(part)
+		}
+	}
+
+	return name
+}
+
+func (n *Enum) StaticAnalysis(posh *types.PoshFile) {
+	// us-east and usEast are the same constant in Go
+	seen := map[string]string{}
+	for _, value := range n.Values {
+		name := value.GetImage()
+		constant := enumConst(n.Identifier.GetImage(), name)
+		if other, ok := seen[constant]; ok && other == name {
+			posh.Errorf(value, "duplicate value %s in enum %s", name, n.Identifier.GetImage())
+		} else if ok {
+			posh.Errorf(value, "values %s and %s of enum %s are both %s in Go", other, name, n.Identifier.GetImage(), constant)
+		}
+		seen[constant] = name
+	}
+
+	// capitalized enums are exported like functions
+	firstLetter := n.Identifier.GetImage()[0]
+	if firstLetter >= 'A' && firstLetter <= 'Z' {
+		posh.Exports[n.Identifier.GetImage()] = n.export()
+	}
+}
+
+// ToGoAst returns the declaration of the type, see constDecl for the values
+func (n *Enum) ToGoAst() ast.Node {
+	return &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{
+			&ast.TypeSpec{
+				Name: &ast.Ident{Name: n.Identifier.GetImage()},
+				Type: &ast.Ident{Name: "string"},
+			},
+		},
+	}
+}
+
+// constDecl returns the declaration of the values of the enum:
+// const ( EnvDev Env = "dev" )
+func (n *Enum) constDecl() ast.Decl {
+	specs := []ast.Spec{}
+	for _, value := range n.Values {
+		specs = append(specs, &ast.ValueSpec{
+			Names: []*ast.Ident{{Name: enumConst(n.Identifier.GetImage(), value.GetImage())}},
+			Type:  &ast.Ident{Name: n.Identifier.GetImage()},
+			Values: []ast.Expr{
+				&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(value.GetImage())},
+			},
+		})
+	}
+
+	return &ast.GenDecl{
+		Tok:    token.CONST,
+		Lparen: 1,
+		Specs:  specs,
+	}
+}
+
+func MatchEnum(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// enum IDENTIFIER { IDENTIFIER (, IDENTIFIER)* ,? }
+
+	if nodes[offset].GetType() != "IDENTIFIER" || nodes[offset].GetImage() != "enum" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	if nodes[offset].GetType() != "IDENTIFIER" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := Enum{
+		BaseNode: types.BaseNode{
+			Type: "ENUM",
+		},
+		Keyword:    nodes[start],
+		Identifier: nodes[offset],
+	}
+
+	offset++
+
+	if !isPunctuator(nodes[offset], "{") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	for !isPunctuator(nodes[offset], "}") {
+		if nodes[offset].GetType() != "IDENTIFIER" {
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+
+		node.Values = append(node.Values, nodes[offset])
+		offset++
+
+		if isPunctuator(nodes[offset], ",") {
+			offset++
+		}
+	}
+
+	// an enum needs a value to be the default
+	if len(node.Values) == 0 {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
@@ -0,0 +1,50 @@
+package rules
+
+import "testing"
+
+const envEnum = "enum Env { dev, prod, us-east }\n"
+
+func TestEnums(t *testing.T) {
+	expectGo(t, []string{envEnum + `
+fn region(env Env) string {
+  return match env {
+    Env.dev => "local",
+    Env.prod, Env.us-east => "eu-1",
+  }
+}
+
+fn main() {
+  io.Println(region(Env.us-east))
+}`},
+		"type Env string",
+		`EnvDev Env = "dev"`,
+		`EnvUsEast Env = "us-east"`,
+		"case EnvDev:",
+		"case EnvProd, EnvUsEast:",
+		"io.Println(region(EnvUsEast))",
+	)
+}
+
+func TestEnumErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"duplicate value", "enum Env { dev, prod, dev }", "duplicate value dev in enum Env"},
+		{"values with the same Go name", "enum Env { us-east, usEast }", "values us-east and usEast of enum Env are both EnvUsEast in Go"},
+		{"missing arms", envEnum + "fn main(env Env) {\n  a = match env { Env.dev => 1 }\n}", "match on Env does not cover prod, us-east"},
+		{"default arm first", envEnum + "fn main() {\n  a = match 3 { _ => 1, 2 => 3 }\n}", "the default arm must be the last one"},
+		{"duplicate enum arms", envEnum + "fn main(env Env) {\n  a = match env { Env.dev => 1, Env.prod, Env.dev => 2, _ => 3 }\n}", "Env.dev is already matched at 3:19"},
+		{"duplicate string arms", "fn main(s string) {\n  a = match s { \"a\" => 1, \"b\", `a` => 2, _ => 3 }\n}", `"a" is already matched at 2:17`},
+		{"duplicate integer arms", "fn main(n int) {\n  a = match n { -1 => 1, 2 => 2, -1 => 3, _ => 4 }\n}", "-1 is already matched at 2:17"},
+		{"duplicate arms of a statement", "fn main(s string) {\n  match s {\n    re`^a` => io.Println(1)\n    \"b\" => io.Println(2)\n    \"b\" => io.Println(3)\n  }\n}", `"b" is already matched at 4:5`},
+		{"arm types", envEnum + "fn main(env Env) {\n  a = match env { Env.dev => 1, _ => \"s\" }\n}", "the arms of a match must have the same type, found int and string"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{test.code}, test.error)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package rules
 
 import (
 	"go/ast"
+	"go/token"
 
 	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
 )
@@ -15,52 +16,426 @@ func (n *SimpleExpression) ToGoAst() ast.Node {
 	return n.Value.ToGoAst()
 }
 
+func (n *SimpleExpression) GetPos() *types.Pos {
+	return n.Value.GetPos()
+}
+
+// Parens is an expression wrapped in parentheses
+type Parens struct {
+	types.BaseNode
+	Value types.Node `json:"value"`
+}
+
+func (n *Parens) ToGoAst() ast.Node {
+	return &ast.ParenExpr{X: valueToGoAst(n.Value)}
+}
+
+func (n *Parens) StaticAnalysis(posh *types.PoshFile) {
+	n.Value.StaticAnalysis(posh)
+}
+
+// Operator precedence, from the loosest to the tightest binding:
+//
+//	|                    pipes, the right hand side is a call
+//	or
+//	and
+//	not                  prefix
+//	== != < <= > >= in   comparisons, a < b < c is (a < b) and (b < c), see
+//	                     ComparisonChain, and membership, x in xs and key in m
+//	+ -
+//	* / %
+//	-                    prefix
+//	xs[i] xs[a:b] x.y    indexes, slices and fields
+//	f(x) a.b (x) [x] {}  calls, field accesses, parentheses, lists and maps
+const (
+	precLowest = iota
+	precPipe
+	precOr
+	precAnd
+	precNot
+	precComparison
+	precAdditive
+	precMultiplicative
+	precUnary
+)
+
+var binaryPrecedence = map[string]int{
+	"|":   precPipe,
+	"or":  precOr,
+	"and": precAnd,
+	"==":  precComparison,
+	"!=":  precComparison,
+	"<":   precComparison,
+	"<=":  precComparison,
+	">":   precComparison,
+	">=":  precComparison,
+	"in":  precComparison,
+	"+":   precAdditive,
+	"-":   precAdditive,
+	"*":   precMultiplicative,
+	"/":   precMultiplicative,
+	"%":   precMultiplicative,
+}
+
+// precedenceOf returns the precedence of the binary operator at the given
+// node, or precLowest if it is not an operator
+func precedenceOf(node types.Node) int {
+	if node.GetType() != "PUNCTUATOR" && node.GetType() != "KEYWORD" {
+		return precLowest
+	}
+
+	return binaryPrecedence[node.GetImage()]
+}
+
+func isPunctuator(node types.Node, image string) bool {
+	return node.GetType() == "PUNCTUATOR" && node.GetImage() == image
+}
+
+// binaryExpr returns lhs op rhs, with parentheses around the operands that
+// bind looser than op
+func binaryExpr(lhs ast.Expr, op token.Token, rhs ast.Expr) ast.Expr {
+	if x, ok := lhs.(*ast.BinaryExpr); ok && x.Op.Precedence() < op.Precedence() {
+		lhs = &ast.ParenExpr{X: lhs}
+	}
+
+	if y, ok := rhs.(*ast.BinaryExpr); ok && y.Op.Precedence() <= op.Precedence() {
+		rhs = &ast.ParenExpr{X: rhs}
+	}
+
+	return &ast.BinaryExpr{X: lhs, Op: op, Y: rhs}
+}
+
 func MatchExpr(nodes []types.Node, offset int) types.Result {
-	// We are looking for Pipe or simple expression
+	// We are looking for an arrow function, a range or an expression made of
+	// operators, see binaryPrecedence
 
-	// look for PIPE
-	if res := MatchPipe(nodes, offset); res.End > res.Start {
+	// look for ARROW_FUNCTION, the patterns of match arms are not expressions
+	// so x => ... is an arm there
+	if res := MatchArrowFunction(nodes, offset); res.End > res.Start {
 		return res
 	}
 
-	// look for logical expression
-	if res := MatchLogical(nodes, offset); res.End > res.Start {
+	// look for RANGE
+	if res := MatchRange(nodes, offset); res.End > res.Start {
 		return res
 	}
 
-	// look for simple expression
-	if res := MatchSimpleExpression(nodes, offset); res.End > res.Start {
+	return matchBinary(nodes, offset, precLowest)
+}
+
+// matchBinary matches an expression with the operators that bind tighter
+// than minPrecedence
+func matchBinary(nodes []types.Node, offset int, minPrecedence int) types.Result {
+	start := offset
+
+	res := matchUnary(nodes, offset)
+	if res.End <= res.Start {
 		return res
-	} else {
+	}
+
+	lhs := res.Node
+	offset = res.End
+
+	for {
+		precedence := precedenceOf(nodes[offset])
+		if precedence <= minPrecedence {
+			break
+		}
+
+		switch precedence {
+		case precPipe:
+			res = matchPipeStage(nodes, offset, lhs)
+		case precComparison:
+			if nodes[offset].GetImage() == "in" {
+				res = matchMembership(nodes, offset, lhs)
+			} else {
+				res = matchComparison(nodes, offset, lhs)
+			}
+		default:
+			res = matchOperation(nodes, offset, lhs, precedence)
+		}
+
+		if res.End <= res.Start {
+			return res
+		}
+
+		lhs = res.Node
+		offset = res.End
+	}
+
+	return types.Result{Node: lhs, Start: start, End: offset}
+}
+
+// matchOperation matches the operator at offset and its right hand side, lhs
+// is the already matched left hand side
+func matchOperation(nodes []types.Node, offset int, lhs types.Node, precedence int) types.Result {
+	op := nodes[offset]
+
+	// operators are left associative, a - b - c is (a - b) - c
+	res := matchBinary(nodes, offset+1, precedence)
+	if res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	if precedence == precOr || precedence == precAnd {
+		return types.Result{
+			Node: &Logical{
+				BaseNode: types.BaseNode{Type: "LOGICAL"},
+				Lhs:      lhs,
+				Op:       op,
+				Rhs:      res.Node,
+			},
+			Start: offset,
+			End:   res.End,
+		}
+	}
+
+	return types.Result{
+		Node: &ArithmeticNode{
+			BaseNode: types.BaseNode{Type: "ARITHMETIC"},
+			Lhs:      lhs,
+			Op:       op,
+			Rhs:      res.Node,
+		},
+		Start: offset,
+		End:   res.End,
+	}
+}
+
+// matchComparison matches a chain of comparisons, lhs is the already matched
+// left hand side of the first one
+func matchComparison(nodes []types.Node, offset int, lhs types.Node) types.Result {
+	start := offset
+	operands := []types.Node{lhs}
+	ops := []types.Node{}
+
+	for precedenceOf(nodes[offset]) == precComparison && nodes[offset].GetImage() != "in" {
+		ops = append(ops, nodes[offset])
+
+		res := matchBinary(nodes, offset+1, precComparison)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		operands = append(operands, res.Node)
+		offset = res.End
+	}
+
+	return types.Result{Node: comparisonChain(operands, ops), Start: start, End: offset}
+}
+
+// matchPipeStage matches a stage of a pipe: | FunctionCall, src is the
+// source of the pipe or its previous stages
+func matchPipeStage(nodes []types.Node, offset int, src types.Node) types.Result {
+	start := offset
+	offset++
+
+	res := MatchFunctionCall(nodes, offset)
+	if res.End <= res.Start {
 		return types.Result{FailedAt: res.FailedAt}
 	}
 
+	call := res.Node.(*FunctionCall)
+	call.InPipe = true
+
+	node := Pipe{
+		BaseNode: types.BaseNode{
+			Type: "PIPE",
+		},
+		Value: call,
+	}
+
+	// The source of a pipe is passed to the first stage, which then is passed
+	// to the next stage: src | f(a) | g() is g(f(ctx, src, a))
+	if prev, ok := src.(*Pipe); ok {
+		call.Args = append([]types.Node{prev.Value}, call.Args...)
+	} else {
+		call.Args = append([]types.Node{&RunContext{}, src}, call.Args...)
+	}
+
+	return types.Result{Node: &node, Start: start, End: res.End}
 }
 
-func MatchSimpleExpression(nodes []types.Node, offset int) types.Result {
+func matchUnary(nodes []types.Node, offset int) types.Result {
 	start := offset
 
 	// We are looking for one of the following:
-	// - ARITHMETIC
-	// - RANGE
-	// - COMPARISON
+	// - NOT EXPRESSION
+	// - - EXPRESSION
+	// - PRIMARY
+
+	if nodes[offset].GetType() == "KEYWORD" && nodes[offset].GetImage() == "not" {
+		res := matchBinary(nodes, offset+1, precNot)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node := Negation{
+			BaseNode: types.BaseNode{
+				Type: "NEGATION",
+			},
+			Value: res.Node,
+		}
+
+		return types.Result{Node: &node, Start: start, End: res.End}
+	}
+
+	if nodes[offset].GetType() == "PUNCTUATOR" && nodes[offset].GetImage() == "-" {
+		res := matchBinary(nodes, offset+1, precUnary)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node := Unary{
+			BaseNode: types.BaseNode{
+				Type: "UNARY",
+			},
+			Op:    nodes[offset],
+			Value: res.Node,
+		}
+
+		return types.Result{Node: &node, Start: start, End: res.End}
+	}
+
+	return matchPostfix(nodes, offset)
+}
+
+// matchPostfix matches a primary expression followed by indexes, slices and
+// fields: xs[1][2:], hosts[0].name
+func matchPostfix(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	res := MatchPrimary(nodes, offset)
+	if res.End <= res.Start {
+		return res
+	}
+
+	value := res.Node
+	offset = res.End
+
+	for {
+		if isPunctuator(nodes[offset], "[") {
+			res = matchIndex(nodes, offset, value)
+			if res.End <= res.Start {
+				return res
+			}
+
+			value = res.Node
+			offset = res.End
+		} else if isPunctuator(nodes[offset], ".") && nodes[offset+1].GetType() == "IDENTIFIER" && isPunctuator(nodes[offset+2], "(") {
+			// a method call on the value: lines(text).join(","), see
+			// methodAnalysis
+			call := &FunctionCall{
+				BaseNode: types.BaseNode{
+					Type: "FUNCTION_CALL",
+				},
+				Callable: &Field{
+					BaseNode: types.BaseNode{
+						Type: "FIELD_ACCESS",
+					},
+					Value: value,
+					Name:  nodes[offset+1],
+				},
+			}
+
+			res = matchArgs(nodes, offset+2, call)
+			if res.End <= res.Start {
+				return res
+			}
+
+			value = call
+			offset = res.End
+		} else if isPunctuator(nodes[offset], ".") && nodes[offset+1].GetType() == "IDENTIFIER" {
+			value = &Field{
+				BaseNode: types.BaseNode{
+					Type: "FIELD_ACCESS",
+				},
+				Value: value,
+				Name:  nodes[offset+1],
+			}
+
+			offset += 2
+		} else {
+			break
+		}
+	}
+
+	return types.Result{Node: value, Start: start, End: offset}
+}
+
+func MatchPrimary(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for one of the following:
+	// - ( EXPRESSION )
+	// - HEREDOC
+	// - TEMPLATE
+	// - LIST
+	// - MAP
+	// - MATCH
+	// - LAMBDA
+	// - RECORD_LITERAL
 	// - CALL
-	// - NUMERIC
+	// - DOT_NOTATION
+	// - INTEGER, FLOAT or IDENTIFIER
 	// - STRING
 	// - BOOLEAN
 
-	// try to match ARITHMETIC
-	if res := MatchArithmetic(nodes, offset); res.End > res.Start {
+	// try to match ( EXPRESSION )
+	if nodes[offset].GetType() == "PUNCTUATOR" && nodes[offset].GetImage() == "(" {
+		defer allowLiterals()()
+
+		res := MatchExpr(nodes, offset+1)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		if nodes[res.End].GetType() != "PUNCTUATOR" || nodes[res.End].GetImage() != ")" {
+			return types.Result{FailedAt: &nodes[res.End]}
+		}
+
+		node := Parens{
+			BaseNode: types.BaseNode{
+				Type: "PARENS",
+			},
+			Value: res.Node,
+		}
+
+		return types.Result{Node: &node, Start: start, End: res.End + 1}
+	}
+
+	// try to match HEREDOC
+	if res := MatchHeredoc(nodes, offset); res.End > res.Start {
 		return res
 	}
 
-	// try to match RANGE
-	if res := MatchRange(nodes, offset); res.End > res.Start {
+	// try to match TEMPLATE
+	if res := MatchTemplate(nodes, offset); res.End > res.Start {
+		return res
+	}
+
+	// try to match LIST
+	if res := MatchList(nodes, offset); res.End > res.Start {
+		return res
+	}
+
+	// try to match MAP
+	if res := MatchMap(nodes, offset); res.End > res.Start {
 		return res
 	}
 
-	// try to match COMPARISON
-	if res := MatchComparison(nodes, offset); res.End > res.Start {
+	// try to match MATCH
+	if res := MatchMatch(nodes, offset); res.End > res.Start {
+		return res
+	}
+
+	// try to match LAMBDA
+	if res := MatchLambda(nodes, offset); res.End > res.Start {
+		return res
+	}
+
+	// try to match RECORD_LITERAL
+	if res := MatchRecordLiteral(nodes, offset); res.End > res.Start {
 		return res
 	}
 
@@ -69,22 +444,40 @@ func MatchSimpleExpression(nodes []types.Node, offset int) types.Result {
 		return res
 	}
 
-	// try to match NUMERIC
-	if res := MatchNumeric(nodes, offset); res.End > res.Start {
+	// try to match DOT_NOTATION
+	if res := MatchDotNotation(nodes, offset); res.End > res.Start {
 		return res
 	}
 
-	// try to match the rest
-	if nodes[offset].GetType() != "STRING" && nodes[offset].GetType() != "BOOLEAN" {
-		return types.Result{FailedAt: &nodes[offset]}
-	}
+	switch nodes[offset].GetType() {
+	case "INTEGER", "FLOAT", "IDENTIFIER":
+		node := Numeric{
+			BaseNode: types.BaseNode{
+				Type: "NUMERIC",
+			},
+			Value: nodes[offset],
+		}
 
-	node := SimpleExpression{
-		BaseNode: types.BaseNode{
-			Type: "SIMPLE_EXPRESSION",
-		},
-		Value: nodes[offset],
+		return types.Result{Node: &node, Start: start, End: offset + 1}
+	case "STRING":
+		node := SimpleExpression{
+			BaseNode: types.BaseNode{
+				Type: "SIMPLE_EXPRESSION",
+			},
+			Value: nodes[offset],
+		}
+
+		return types.Result{Node: &node, Start: start, End: offset + 1}
+	case "BOOLEAN":
+		node := Boolean{
+			BaseNode: types.BaseNode{
+				Type: "BOOLEAN",
+			},
+			Value: nodes[offset],
+		}
+
+		return types.Result{Node: &node, Start: start, End: offset + 1}
 	}
 
-	return types.Result{Node: &node, Start: start, End: offset + 1}
+	return types.Result{FailedAt: &nodes[offset]}
 }
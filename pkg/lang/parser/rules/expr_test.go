@@ -0,0 +1,46 @@
+package rules
+
+import "testing"
+
+// exprProgram returns a program that assigns expr to x, with a, b and c ints,
+// p, q and r bools and xs a list of ints
+func exprProgram(expr string) []string {
+	return []string{`fn f(a int, b int, c int, p bool, q bool, r bool, xs []int) void {
+  x = ` + expr + `
+  io.Println(x)
+}`}
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		expr string
+		goIs string
+	}{
+		{"a + b * c", "x := a + b*c"},
+		{"a * b + c", "x := a*b + c"},
+		{"(a + b) * c", "x := (a + b) * c"},
+		{"a - b - c", "x := a - b - c"},
+		{"a - (b - c)", "x := a - (b - c)"},
+		{"a / b % c", "x := a / b % c"},
+		{"-a * b", "x := -a * b"},
+		{"a + b < c * 2", "x := a+b < c*2"},
+		{"p or q and r", "x := p || q && r"},
+		{"(p or q) and r", "x := (p || q) && r"},
+		{"not p and q", "x := !p && q"},
+		{"not a < b", "x := !(a < b)"},
+		{"a == b or p", "x := a == b || p"},
+		{"a + 1 in xs and p", "x := std.Contains(xs, a+1) && p"},
+		{"xs[a + 1] * 2", "x := xs[a+1] * 2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expectGo(t, exprProgram(test.expr), test.goIs)
+		})
+	}
+}
+
+func TestExprParseErrors(t *testing.T) {
+	expectError(t, exprProgram("a * * b"), "failed to parse")
+	expectError(t, exprProgram("(a + b"), "failed to parse")
+}
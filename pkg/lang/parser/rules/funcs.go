@@ -3,6 +3,8 @@ package rules
 import (
 	"go/ast"
 	"go/token"
+	"strconv"
+	"strings"
 
 	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
 )
@@ -11,6 +13,13 @@ type Param struct {
 	types.BaseNode
 	Identifier types.Node `json:"identifier"`
 	ParamType  types.Node `json:"paramType"`
+	// Default is the value of the param when it is left out: port int = 8080
+	Default types.Node `json:"default"`
+	// Variadic is set for a param that takes the rest of the arguments as a
+	// list: args ...string
+	Variadic bool `json:"variadic"`
+	// EnumValues are the values of the type of the param if it is an enum
+	EnumValues []string `json:"enumValues"`
 }
 
 type Parameters struct {
@@ -18,9 +27,25 @@ type Parameters struct {
 	Params []Param `json:"params"`
 }
 
+// valueType returns the type of the param inside of the function, a variadic
+// param is a list
+func (n *Param) valueType() string {
+	if n.Variadic {
+		return "[]" + n.ParamType.GetImage()
+	}
+
+	return n.ParamType.GetImage()
+}
+
+// returnKey marks the scope of a function in the environment, its value is
+// the result type of the function
+const returnKey = "@return"
+
 type ReturnStatement struct {
 	types.BaseNode
-	Expression *types.Node `json:"expression"`
+	Keyword types.Node `json:"keyword"`
+	// Values has more than one value when the function returns a tuple
+	Values []types.Node `json:"values"`
 }
 
 func (n *ReturnStatement) ToGoStatementAst() ast.Stmt {
@@ -28,37 +53,53 @@ func (n *ReturnStatement) ToGoStatementAst() ast.Stmt {
 		Results: []ast.Expr{},
 	}
 
-	if n.Expression != nil {
-		expr := (*n.Expression).ToGoAst().(ast.Expr)
+	for _, value := range n.Values {
+		r.Results = append(r.Results, valueToGoAst(value))
+	}
 
-		// if expr is a PIPE, we need to add .Wait().ToString() to it
-		if (*n.Expression).GetType() == "PIPE" {
-			expr = &ast.CallExpr{
-				Fun: &ast.SelectorExpr{
-					X:   expr,
-					Sel: &ast.Ident{Name: "Wait"},
-				},
-				Args: []ast.Expr{},
-			}
+	return r
+}
 
-			expr = &ast.CallExpr{
-				Fun: &ast.SelectorExpr{
-					X:   expr,
-					Sel: &ast.Ident{Name: "ToString"},
-				},
-				Args: []ast.Expr{},
-			}
+func (n *ReturnStatement) StaticAnalysis(posh *types.PoshFile) {
+	if block, ok := enclosingBlock(posh); ok {
+		posh.Errorf(n.Keyword, "return is not allowed inside of a %s block", block)
+	}
+
+	resultType, ok := posh.Environment.Get(returnKey)
+	results := tupleTypes(resultType)
+	if resultType == "void" {
+		results = []string{}
+	} else if results == nil {
+		results = []string{resultType}
+	}
+
+	for i, value := range n.Values {
+		// an arrow function takes its type from the result
+		if len(n.Values) == len(results) {
+			expectType(value, results[i])
 		}
 
-		r.Results = append(r.Results, expr)
+		value.StaticAnalysis(posh)
 	}
 
-	return r
-}
+	if !ok {
+		return
+	}
 
-func (n *ReturnStatement) StaticAnalysis(posh *types.PoshFile) {
-	if n.Expression != nil {
-		(*n.Expression).StaticAnalysis(posh)
+	// a tuple can be returned as a whole: return parse(line)
+	if len(n.Values) == 1 && len(results) > 1 {
+		if valueType := typeOf(posh, n.Values[0]); valueType == "" || valueType == resultType {
+			return
+		}
+	}
+
+	if len(n.Values) != len(results) {
+		posh.Errorf(n.Keyword, "the function returns %d values, found %d", len(results), len(n.Values))
+		return
+	}
+
+	for i, value := range n.Values {
+		checkType(posh, []types.Node{value}, results[i], "result of type")
 	}
 }
 
@@ -67,14 +108,29 @@ type FunctionBody struct {
 	Content []types.Node `json:"content"`
 }
 
-func (n *FunctionBody) ToGoAst() ast.Node {
+// multiStatement is implemented by the nodes that compile to more than one
+// statement
+type multiStatement interface {
+	ToGoStatements() []ast.Stmt
+}
+
+// statements returns the Go statements of the content of a body
+func statements(content []types.Node) []ast.Stmt {
 	body := []ast.Stmt{}
-	for _, content := range n.Content {
-		body = append(body, content.ToGoStatementAst())
+	for _, node := range content {
+		if multi, ok := node.(multiStatement); ok {
+			body = append(body, multi.ToGoStatements()...)
+		} else {
+			body = append(body, node.ToGoStatementAst())
+		}
 	}
 
+	return body
+}
+
+func (n *FunctionBody) ToGoAst() ast.Node {
 	return &ast.BlockStmt{
-		List: body,
+		List: statements(n.Content),
 	}
 }
 
@@ -101,6 +157,8 @@ func getFlagVarName(paramType string) string {
 		return "IntVar"
 	case "bool":
 		return "BoolVar"
+	case "float64":
+		return "Float64Var"
 	default:
 		return "StringVar"
 	}
@@ -123,6 +181,11 @@ func getFlagDefaultValue(paramType string) ast.Expr {
 			Kind:  token.STRING,
 			Value: "false",
 		}
+	case "float64":
+		return &ast.BasicLit{
+			Kind:  token.FLOAT,
+			Value: "0",
+		}
 	default:
 		return &ast.BasicLit{
 			Kind:  token.STRING,
@@ -131,22 +194,107 @@ func getFlagDefaultValue(paramType string) ast.Expr {
 	}
 }
 
-func (n *Function) ToGoAst() ast.Node {
-	funcType := &ast.FuncType{}
+// flagDefault returns the default value of the flag of a param of main
+func flagDefault(param Param) ast.Expr {
+	if param.Default != nil {
+		return param.Default.ToGoAst().(ast.Expr)
+	}
 
-	if n.ReturnType != nil && (*n.ReturnType).GetImage() != "void" {
-		funcType.Results = &ast.FieldList{
-			List: []*ast.Field{
-				{
-					Type: (*n.ReturnType).ToGoAst().(ast.Expr),
+	return getFlagDefaultValue(param.ParamType.GetImage())
+}
+
+// enumFlag returns the declaration of a flag that only takes the values of an
+// enum, its default is the first value unless the param has one:
+//
+//	var env Env = EnvDev
+//	flag.Var(std.EnumFlag(&env, EnvDev, EnvProd), "env", "one of dev, prod")
+func enumFlag(param Param) []ast.Stmt {
+	enum := param.ParamType.GetImage()
+	name := param.Identifier.GetImage()
+
+	args := []ast.Expr{&ast.UnaryExpr{Op: token.AND, X: &ast.Ident{Name: name}}}
+	for _, value := range param.EnumValues {
+		args = append(args, &ast.Ident{Name: enumConst(enum, value)})
+	}
+
+	value := args[1]
+	if param.Default != nil {
+		value = param.Default.ToGoAst().(ast.Expr)
+	}
+
+	return []ast.Stmt{
+		&ast.DeclStmt{
+			Decl: &ast.GenDecl{
+				Tok: token.VAR,
+				Specs: []ast.Spec{
+					&ast.ValueSpec{
+						Names:  []*ast.Ident{{Name: name}},
+						Type:   &ast.Ident{Name: enum},
+						Values: []ast.Expr{value},
+					},
 				},
 			},
-		}
+		},
+		&ast.ExprStmt{
+			X: &ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "flag"},
+					Sel: &ast.Ident{Name: "Var"},
+				},
+				Args: []ast.Expr{
+					&ast.CallExpr{
+						Fun: &ast.SelectorExpr{
+							X:   &ast.Ident{Name: "std"},
+							Sel: &ast.Ident{Name: "EnumFlag"},
+						},
+						Args: args,
+					},
+					&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(name)},
+					&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("one of " + strings.Join(param.EnumValues, ", "))},
+				},
+			},
+		},
+	}
+}
+
+func (n *Function) ToGoAst() ast.Node {
+	funcType := &ast.FuncType{}
+
+	if n.ReturnType != nil {
+		funcType.Results = resultFields((*n.ReturnType).GetImage())
 	}
 
 	body := n.Body.ToGoAst().(*ast.BlockStmt)
 
 	if n.Identifier.GetImage() == "main" {
+		// main can return an exit code or an error but Go's main can't, so the
+		// body is wrapped in a function literal and its result is passed to:
+		// std.ExitWith(func() int { ... }())
+		if funcType.Results != nil {
+			body = &ast.BlockStmt{
+				List: []ast.Stmt{
+					&ast.ExprStmt{
+						X: &ast.CallExpr{
+							Fun: &ast.SelectorExpr{
+								X:   &ast.Ident{Name: "std"},
+								Sel: &ast.Ident{Name: "ExitWith"},
+							},
+							Args: []ast.Expr{
+								&ast.CallExpr{
+									Fun: &ast.FuncLit{
+										Type: funcType,
+										Body: body,
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			funcType = &ast.FuncType{}
+		}
+
 		// main function params should be turned into command line arguments
 		// using flag package and added to the main function body:
 		// fn main(name string, age int) should be turned into:
@@ -157,7 +305,28 @@ func (n *Function) ToGoAst() ast.Node {
 		//     age := flag.IntVar(&age, "age", "", "")
 		//     flag.Parse()
 		// }
-		// parameter types should be used to determine the type of the flag
+		// parameter types should be used to determine the type of the flag,
+		// a variadic param takes the arguments after the flags:
+		//     files := flag.Args()
+
+		params := n.Params.Params
+		if len(params) > 0 && params[len(params)-1].Variadic {
+			param := params[len(params)-1]
+			params = params[:len(params)-1]
+
+			body.List = append([]ast.Stmt{&ast.AssignStmt{
+				Lhs: []ast.Expr{param.Identifier.ToGoAst().(*ast.Ident)},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{
+					&ast.CallExpr{
+						Fun: &ast.SelectorExpr{
+							X:   &ast.Ident{Name: "flag"},
+							Sel: &ast.Ident{Name: "Args"},
+						},
+					},
+				},
+			}}, body.List...)
+		}
 
 		body.List = append([]ast.Stmt{&ast.ExprStmt{
 			X: &ast.CallExpr{
@@ -168,8 +337,14 @@ func (n *Function) ToGoAst() ast.Node {
 			},
 		}}, body.List...)
 
-		for i := len(n.Params.Params) - 1; i >= 0; i-- {
-			param := n.Params.Params[i]
+		for i := len(params) - 1; i >= 0; i-- {
+			param := params[i]
+
+			if len(param.EnumValues) > 0 {
+				body.List = append(enumFlag(param), body.List...)
+				continue
+			}
+
 			body.List = append([]ast.Stmt{
 				&ast.DeclStmt{
 					Decl: &ast.GenDecl{
@@ -197,7 +372,7 @@ func (n *Function) ToGoAst() ast.Node {
 								Kind:  token.STRING,
 								Value: "\"" + param.Identifier.GetImage() + "\"",
 							},
-							getFlagDefaultValue(param.ParamType.GetImage()),
+							flagDefault(param),
 							&ast.BasicLit{
 								Kind:  token.STRING,
 								Value: "\"\"",
@@ -208,13 +383,28 @@ func (n *Function) ToGoAst() ast.Node {
 			}, body.List...)
 		}
 
+		// unhandled errors, such as failed commands, terminate the program
+		// with the matching exit code: defer std.HandleExit()
+		body.List = append([]ast.Stmt{&ast.DeferStmt{
+			Call: &ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "std"},
+					Sel: &ast.Ident{Name: "HandleExit"},
+				},
+			},
+		}}, body.List...)
 	} else {
 		params := []*ast.Field{}
 
 		for _, param := range n.Params.Params {
+			paramType := param.ParamType.ToGoAst().(ast.Expr)
+			if param.Variadic {
+				paramType = &ast.Ellipsis{Elt: paramType}
+			}
+
 			params = append(params, &ast.Field{
 				Names: []*ast.Ident{param.Identifier.ToGoAst().(*ast.Ident)},
-				Type:  param.ParamType.ToGoAst().(ast.Expr),
+				Type:  paramType,
 			})
 		}
 
@@ -230,36 +420,149 @@ func (n *Function) ToGoAst() ast.Node {
 	}
 }
 
+// export returns the signature of the function
+func (n *Function) export() types.Export {
+	export := types.Export{
+		Type:   "void",
+		IsFunc: true,
+		Params: []types.Param{},
+	}
+
+	if n.ReturnType != nil {
+		export.Type = (*n.ReturnType).GetImage()
+	}
+
+	for _, param := range n.Params.Params {
+		export.Params = append(export.Params, types.Param{
+			Name:     param.Identifier.GetImage(),
+			Type:     param.ParamType.GetImage(),
+			Default:  param.Default,
+			Variadic: param.Variadic,
+		})
+	}
+
+	return export
+}
+
+// funcType returns the Go type of a function: func(string, int) bool
+func funcType(export types.Export) string {
+	params := []string{}
+	for _, param := range export.Params {
+		if param.Variadic {
+			params = append(params, "..."+param.Type)
+		} else {
+			params = append(params, param.Type)
+		}
+	}
+
+	signature := "func(" + strings.Join(params, ", ") + ")"
+	if export.Type != "void" {
+		signature += " " + export.Type
+	}
+
+	return signature
+}
+
+// resultType returns the result type of a function type, or an empty string
+// if the function has none
+func resultType(funcType string) string {
+	// the parameters can be functions too: func(func(int) int) int
+	depth := 0
+	for i, c := range funcType {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(funcType[i+1:])
+			}
+		}
+	}
+
+	return ""
+}
+
+// isConstant tells if a node is a literal or the value of an enum, which are
+// the values that can be used as defaults of params
+func isConstant(node types.Node) bool {
+	switch n := node.(type) {
+	case *types.TokenNode:
+		return includes([]string{"STRING", "INTEGER", "FLOAT", "BOOLEAN"}, n.Type)
+	case *Numeric:
+		return isConstant(n.Value)
+	case *SimpleExpression:
+		return isConstant(n.Value)
+	case *Parens:
+		return isConstant(n.Value)
+	case *Unary:
+		return isConstant(n.Value)
+	case *Boolean:
+		return true
+	case *DotNotation:
+		return n.Enum != ""
+	}
+
+	return false
+}
+
 func (n *Function) StaticAnalysis(posh *types.PoshFile) {
+	// defaults are evaluated by the callers, so they can not use the params
+	// or anything else that is local to the function
+	for i, param := range n.Params.Params {
+		if param.Variadic && i < len(n.Params.Params)-1 {
+			posh.Errorf(param.Identifier, "only the last parameter can be variadic")
+		}
+
+		if param.Variadic && n.Identifier.GetImage() == "main" && param.ParamType.GetImage() != "string" {
+			posh.Errorf(param.ParamType, "the variadic parameter of main takes the arguments after the flags, it must be ...string")
+		}
+
+		if param.Default == nil {
+			continue
+		}
+
+		if param.Variadic {
+			posh.Errorf(param.Default, "a variadic parameter can not have a default value")
+			continue
+		}
+
+		param.Default.StaticAnalysis(posh)
+		if !isConstant(param.Default) {
+			posh.Errorf(param.Default, "the default value of %s must be a literal or an enum value", param.Identifier.GetImage())
+		} else {
+			checkType(posh, []types.Node{param.Default}, param.ParamType.GetImage(), "default value of type")
+		}
+	}
+
 	posh.Environment.PushScope()
+	posh.Environment.Set(returnKey, n.export().Type)
+	posh.Labels = map[string]bool{}
 
-	for _, param := range n.Params.Params {
-		posh.Environment.Set(param.Identifier.GetImage(), param.ParamType.GetImage())
+	for i, param := range n.Params.Params {
+		posh.Environment.Set(param.Identifier.GetImage(), param.valueType())
+
+		if enum, ok := posh.Enums[param.ParamType.GetImage()]; ok && n.Identifier.GetImage() == "main" {
+			n.Params.Params[i].EnumValues = enum.Values
+		}
 	}
 
 	// if the function name starts with a capital letter, it's a public function
 	// and should be added to the export list
 	firstLetter := n.Identifier.GetImage()[0]
 	if firstLetter >= 'A' && firstLetter <= 'Z' {
-		expDef := types.Export{
-			Type:   (*n.ReturnType).GetImage(),
-			IsFunc: true,
-			Params: []types.Param{},
-		}
-
-		for _, param := range n.Params.Params {
-			expDef.Params = append(expDef.Params, types.Param{
-				Name: param.Identifier.GetImage(),
-				Type: param.ParamType.GetImage(),
-			})
-		}
-
-		posh.Exports[n.Identifier.GetImage()] = expDef
+		posh.Exports[n.Identifier.GetImage()] = n.export()
 	}
 
-	// if the function is main and has parameters, we need to add the flag package
-	if n.Identifier.GetImage() == "main" && len(n.Params.Params) > 0 {
+	// main parses the command line flags and handles exit codes, so
+	// we need to add the flag and std packages
+	if n.Identifier.GetImage() == "main" {
 		posh.StdImports["flag"] = true
+		posh.StdImports["std"] = true
+
+		if n.ReturnType != nil && !includes([]string{"void", "int", "error"}, (*n.ReturnType).GetImage()) {
+			posh.Errorf(*n.ReturnType, "main must return int or error, not %s", (*n.ReturnType).GetImage())
+		}
 	}
 
 	n.Body.StaticAnalysis(posh)
@@ -299,13 +602,30 @@ func MatchFunctionParams(nodes []types.Node, offset int) types.Result {
 
 		offset++
 
-		if nodes[offset].GetType() != "IDENTIFIER" {
-			return types.Result{FailedAt: &nodes[offset]}
+		if isPunctuator(nodes[offset], "...") {
+			param.Variadic = true
+			offset++
+		}
+
+		res := MatchType(nodes, offset)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		param.ParamType = res.Node
+		offset = res.End
+
+		if isPunctuator(nodes[offset], "=") {
+			res := MatchExpr(nodes, offset+1)
+			if res.End <= res.Start {
+				return types.Result{FailedAt: res.FailedAt}
+			}
+
+			param.Default = res.Node
+			offset = res.End
 		}
 
-		param.ParamType = nodes[offset]
 		node.Params = append(node.Params, param)
-		offset++
 
 		if nodes[offset].GetType() == "PUNCTUATOR" && nodes[offset].GetImage() == "," {
 			offset++
@@ -328,11 +648,23 @@ func MatchReturnStatement(nodes []types.Node, offset int) types.Result {
 		BaseNode: types.BaseNode{
 			Type: "RETURN_STATEMENT",
 		},
+		Keyword: nodes[start],
 	}
 
+	// try to match EXPRESSION (, EXPRESSION)*
 	if res := MatchExpr(nodes, offset); res.End > res.Start {
-		node.Expression = &res.Node
+		node.Values = append(node.Values, res.Node)
 		offset = res.End
+
+		for isPunctuator(nodes[offset], ",") {
+			res := MatchExpr(nodes, offset+1)
+			if res.End <= res.Start {
+				return types.Result{FailedAt: res.FailedAt}
+			}
+
+			node.Values = append(node.Values, res.Node)
+			offset = res.End
+		}
 	}
 
 	return types.Result{Node: &node, Start: start, End: offset}
@@ -348,6 +680,50 @@ func includes(arr []string, item string) bool {
 	return false
 }
 
+func MatchStatement(nodes []types.Node, offset int) types.Result {
+	// We are looking for one of the following:
+	// - DESTRUCTURING
+	// - ASSIGNMENT
+	// - MATCH
+	// - PIPE
+	// - FUNCTION_CALL
+	// - RETURN_STATEMENT
+	// - IF
+	// - FOR
+	// - WHILE
+	// - BREAK or CONTINUE
+	// - SANDBOX
+	// - RETRY
+
+	if res := MatchDestructuring(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchAssignment(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchMatchStatement(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchPipe(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchFunctionCall(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchReturnStatement(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchIfStatement(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchForLoop(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchWhileLoop(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchForControl(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchSandbox(nodes, offset); res.End > res.Start {
+		return res
+	} else if res := MatchRetry(nodes, offset); res.End > res.Start {
+		return res
+	}
+
+	return types.Result{FailedAt: &nodes[offset]}
+}
+
 func MatchFunctionBody(nodes []types.Node, offset int) types.Result {
 	start := offset
 
@@ -355,6 +731,7 @@ func MatchFunctionBody(nodes []types.Node, offset int) types.Result {
 		return types.Result{FailedAt: &nodes[offset]}
 	}
 
+	defer allowLiterals()()
 	offset++
 
 	node := FunctionBody{
@@ -369,19 +746,7 @@ func MatchFunctionBody(nodes []types.Node, offset int) types.Result {
 			break
 		}
 
-		if res := MatchAssignment(nodes, offset); res.End > res.Start {
-			node.Content = append(node.Content, res.Node)
-			offset = res.End
-		} else if res := MatchFunctionCall(nodes, offset); res.End > res.Start {
-			node.Content = append(node.Content, res.Node)
-			offset = res.End
-		} else if res := MatchReturnStatement(nodes, offset); res.End > res.Start {
-			node.Content = append(node.Content, res.Node)
-			offset = res.End
-		} else if res := MatchIfStatement(nodes, offset); res.End > res.Start {
-			node.Content = append(node.Content, res.Node)
-			offset = res.End
-		} else if res := MatchForLoop(nodes, offset); res.End > res.Start {
+		if res := MatchStatement(nodes, offset); res.End > res.Start {
 			node.Content = append(node.Content, res.Node)
 			offset = res.End
 		} else {
@@ -422,13 +787,12 @@ func MatchFunction(nodes []types.Node, offset int) types.Result {
 	node.Params = res.Node.(*Parameters)
 	offset = res.End
 
-	if node.Identifier.GetImage() != "main" {
-		if nodes[offset].GetType() != "IDENTIFIER" {
-			return types.Result{FailedAt: &nodes[offset]}
-		}
-
-		node.ReturnType = &nodes[offset]
-		offset++
+	// main may omit its return type
+	if res := MatchResultType(nodes, offset); res.End > res.Start {
+		node.ReturnType = &res.Node
+		offset = res.End
+	} else if node.Identifier.GetImage() != "main" {
+		return types.Result{FailedAt: res.FailedAt}
 	}
 
 	res = MatchFunctionBody(nodes, offset)
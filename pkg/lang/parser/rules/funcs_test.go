@@ -0,0 +1,72 @@
+package rules
+
+import "testing"
+
+func TestMainResults(t *testing.T) {
+	expectGo(t, []string{`fn main(fail bool) int {
+  if fail {
+    exit(3)
+  }
+  return 0
+}`},
+		"func main() {",
+		"defer std.HandleExit()",
+		"std.ExitWith(func() int {",
+		"std.Exit(3)",
+		"return 0",
+		"}())",
+	)
+
+	expectGo(t, []string{`fn main() error {
+  ls("/")
+  return none
+}`},
+		"defer std.HandleExit()",
+		"std.ExitWith(func() error {",
+		`exec.Run(ls(&exec.RunContext{}, "/"))`,
+	)
+
+	// a main without a result only handles the failures of commands
+	expectGo(t, []string{`fn main() {
+  ls("/")
+}`},
+		"func main() {",
+		"defer std.HandleExit()",
+		`exec.Run(ls(&exec.RunContext{}, "/"))`,
+	)
+}
+
+func TestMainResultErrors(t *testing.T) {
+	expectError(t, []string{"fn main() string {\n  return \"a\"\n}"}, "main must return int or error, not string")
+}
+
+func TestExitShadowing(t *testing.T) {
+	// a function named exit replaces the builtin
+	expectGo(t, []string{`fn exit(code int) void {
+  io.Println(code)
+}
+
+fn main() {
+  exit(1)
+}`},
+		"exit(1)",
+	)
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		err   string
+	}{
+		{"parse error", []string{`from "/mod1.posh" import F`, "fn F() void {"}, "main.posh:1:6: failed to import /mod1.posh"},
+		{"semantic error", []string{`from "/mod1.posh" import F`, "fn F() string {\n  return 1\n}"}, "mod1.posh:2:10: "},
+		{"missing name", []string{`from "/mod1.posh" import G`, "fn F() void {\n}"}, "main.posh:1:26: G is not exported by /mod1.posh"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, test.files, test.err)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package rules
+
+import (
+	"go/ast"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// Heredoc is a multi-line string that is fed to the stdin of the first command
+// of a pipe when it is the source of the pipe
+type Heredoc struct {
+	types.BaseNode
+	Marker types.Node `json:"marker"`
+	Value  types.Node `json:"value"`
+}
+
+func (n *Heredoc) GetPos() *types.Pos {
+	return n.Marker.GetPos()
+}
+
+func (n *Heredoc) ToGoAst() ast.Node {
+	return n.Value.ToGoAst()
+}
+
+func (n *Heredoc) StaticAnalysis(posh *types.PoshFile) {
+	n.Value.StaticAnalysis(posh)
+}
+
+func MatchHeredoc(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// HEREDOC (STRING | TEMPLATE)
+
+	if nodes[offset].GetType() != "HEREDOC" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := Heredoc{
+		BaseNode: types.BaseNode{
+			Type: "HEREDOC",
+		},
+		Marker: nodes[offset],
+	}
+
+	offset++
+
+	if res := MatchTemplate(nodes, offset); res.End > res.Start {
+		node.Value = res.Node
+		return types.Result{Node: &node, Start: start, End: res.End}
+	}
+
+	if nodes[offset].GetType() != "STRING" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node.Value = nodes[offset]
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
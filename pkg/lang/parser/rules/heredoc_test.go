@@ -0,0 +1,18 @@
+package rules
+
+import "testing"
+
+func TestHeredocs(t *testing.T) {
+	expectGo(t, []string{`fn f(name string) void {
+  <<-EOF
+    name: ${name}
+    EOF | kubectl("apply", -f, -)
+  x = <<'EOF'
+${name}
+EOF
+  io.Println(x)
+}`},
+		`exec.Run(kubectl(&exec.RunContext{Stdout: exec.Input(io.Format("name: %s\n", name))}, "apply", "-f", "-"))`,
+		`x := "${name}\n"`,
+	)
+}
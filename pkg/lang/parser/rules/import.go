@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"strconv"
 	"strings"
 
 	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
@@ -23,14 +24,19 @@ type Import struct {
 	Imports []*ImportItem `json:"imports"`
 }
 
+// path returns the module path of the import, without the quotes
+func (n *Import) path() string {
+	return n.Module.(*types.TokenNode).Value
+}
+
 func importPath(imp *Import) string {
-	if isPoshLocalImport(imp.Module) {
-		localPath := utils.Unquote(imp.Module.GetImage())
+	if isPoshLocalImport(imp.path()) {
+		localPath := imp.path()
 		localPath = localPath[:len(localPath)-5]
-		return "\"main" + localPath + "\""
+		return strconv.Quote("main" + localPath)
 	}
 
-	return imp.Module.GetImage()
+	return strconv.Quote(imp.path())
 }
 
 func (n *Import) ToGoAst() ast.Node {
@@ -52,12 +58,19 @@ func (n *Import) ToGoAst() ast.Node {
 	}
 
 	specs := []ast.Spec{}
+	imported := map[string]bool{}
 	for _, imp := range n.Imports {
-		importName := ImportPathToImportName(n.Module.GetImage())
+		importName := ImportPathToImportName(n.path())
 		if imp.Alias != nil {
 			importName = (*imp.Alias).GetImage()
 		}
 
+		// the names imported from the same module share the import
+		if imported[importName] {
+			continue
+		}
+		imported[importName] = true
+
 		spec := &ast.ImportSpec{
 			Name: &ast.Ident{
 				Name: importName,
@@ -76,9 +89,8 @@ func (n *Import) ToGoAst() ast.Node {
 	}
 }
 
-func isPoshLocalImport(node types.Node) bool {
-	unquoted := utils.Unquote(node.GetImage())
-	return strings.HasPrefix(unquoted, "/") && strings.HasSuffix(unquoted, ".posh")
+func isPoshLocalImport(path string) bool {
+	return strings.HasPrefix(path, "/") && strings.HasSuffix(path, ".posh")
 }
 
 func (n *Import) StaticAnalysis(posh *types.PoshFile) {
@@ -86,12 +98,12 @@ func (n *Import) StaticAnalysis(posh *types.PoshFile) {
 	// e.g. from "fmt" import Println as fmtPrintln then
 	// fmtPrintln = fmt.Println
 	node := ast.ValueSpec{}
-	packageName := ImportPathToImportName(n.Module.GetImage())
+	packageName := ImportPathToImportName(n.path())
 	var modExports map[string]types.Export
 
-	if isPoshLocalImport(n.Module) {
+	if isPoshLocalImport(n.path()) {
 		// We need to compile the local import and get the export definitions
-		importPath := utils.Unquote(n.Module.GetImage())[1:]
+		importPath := n.path()[1:]
 		modPosh := types.NewPoshFile(
 			strings.TrimPrefix(importPath, posh.BaseDir),
 			posh.BaseDir,
@@ -102,14 +114,14 @@ func (n *Import) StaticAnalysis(posh *types.PoshFile) {
 
 		err := utils.CompilePoshFile(modPosh, MatchPosh)
 		if err != nil {
-			// TODO: Handle error
-			panic(err)
+			posh.Errorf(n.Module, "failed to import %s:\n%v", n.path(), err)
+			return
 		}
 
 		// Add the compiled file to the list of compiled files
 		// TODO: Actually use the cache!
-		posh.CompiledFiles[n.Module.GetImage()] = types.CompiledFile{
-			FileName: n.Module.GetImage(),
+		posh.CompiledFiles[n.path()] = types.CompiledFile{
+			FileName: n.path(),
 			Exports:  modPosh.Exports,
 		}
 
@@ -125,23 +137,62 @@ func (n *Import) StaticAnalysis(posh *types.PoshFile) {
 				importAs = packageName
 			}
 
-			node.Names = append(node.Names, imp.Name.ToGoAst().(*ast.Ident))
-			node.Values = append(node.Values, &ast.SelectorExpr{
-				X:   &ast.Ident{Name: importAs},
-				Sel: imp.Name.ToGoAst().(*ast.Ident),
-			})
-
 			importedName := imp.Name.GetImage()
 			importedType, ok := modExports[importedName]
 
 			if !ok {
-				// TODO: Handle error properly
-				panic(fmt.Sprintf("Imported name %s not found in module %s", importedName, n.Module.GetImage()))
+				posh.Errorf(imp.Name, "%s is not exported by %s", importedName, n.path())
+				continue
+			}
+
+			selector := &ast.SelectorExpr{
+				X:   &ast.Ident{Name: importAs},
+				Sel: imp.Name.ToGoAst().(*ast.Ident),
 			}
 
-			posh.Environment.Set(imp.Name.GetImage(), importedType.Type)
+			// records and enums are imported as type aliases, and the values of
+			// enums along with them: type Env = lib.Env; var EnvDev = lib.EnvDev
+			if importedType.IsType {
+				posh.TopLevelTypes = append(posh.TopLevelTypes, &ast.TypeSpec{
+					Name:   imp.Name.ToGoAst().(*ast.Ident),
+					Assign: 1,
+					Type:   selector,
+				})
+
+				if importedType.Values == nil {
+					posh.Records[importedName] = importedType
+					continue
+				}
+
+				posh.Enums[importedName] = importedType
+				for _, value := range importedType.Values {
+					name := enumConst(importedName, value)
+					node.Names = append(node.Names, &ast.Ident{Name: name})
+					node.Values = append(node.Values, &ast.SelectorExpr{
+						X:   &ast.Ident{Name: importAs},
+						Sel: &ast.Ident{Name: name},
+					})
+				}
+				continue
+			}
+
+			if importedType.IsFunc {
+				node.Names = append(node.Names, imp.Name.ToGoAst().(*ast.Ident))
+				node.Values = append(node.Values, selector)
+
+				importedType.Package = importAs
+				posh.Functions[imp.Name.GetImage()] = importedType
+				posh.Environment.Set(imp.Name.GetImage(), funcType(importedType))
+			} else {
+				// imported variables are used through their package, so the
+				// changes the module makes to them are seen, see importKey
+				posh.Environment.Set(imp.Name.GetImage(), importedType.Type)
+				posh.Environment.Set(importKey(imp.Name.GetImage()), importAs)
+				posh.Environment.Set(constKey(imp.Name.GetImage()), "imported at "+imp.Name.GetPos().String())
+				posh.Environment.Set(topLevelKey(imp.Name.GetImage()), "true")
+			}
 		} else if imp.Name.GetImage() == "*" {
-			posh.Environment.Set(packageName, fmt.Sprintf("module:%s", n.Module.GetImage()))
+			posh.Environment.Set(packageName, fmt.Sprintf("module:%s", n.path()))
 		}
 	}
 
@@ -150,10 +201,25 @@ func (n *Import) StaticAnalysis(posh *types.PoshFile) {
 	}
 }
 
-func ImportPathToImportName(path string) string {
-	unquoted := utils.Unquote(path)
+// importKey marks the variables imported from other modules, its value is
+// the Go package they are in: Count is lib.Count
+func importKey(name string) string {
+	return "@import:" + name
+}
 
-	parts := strings.Split(unquoted, "/")
+// importedPackage returns the package of a name that is a variable imported
+// from another module, or "" when it's not one or when it is shadowed
+func importedPackage(posh *types.PoshFile, name string) string {
+	pkg, importScope, ok := posh.Environment.Lookup(importKey(name))
+	if _, scope, _ := posh.Environment.Lookup(name); !ok || scope != importScope {
+		return ""
+	}
+
+	return pkg
+}
+
+func ImportPathToImportName(path string) string {
+	parts := strings.Split(path, "/")
 	name := parts[len(parts)-1]
 
 	// remove extension
@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+const counterModule = `type Host {
+  name string
+}
+
+const REGION = "eu-1"
+var Count = 0
+var Primary = Host{name: "db"}
+
+fn Bump() void {
+  Count += 1
+}
+`
+
+func TestImportedVariables(t *testing.T) {
+	expectGo(t, []string{`from "/mod1.posh" import Bump, Count, Primary, REGION
+
+fn f(Count int) int {
+  return Count
+}
+
+fn main() {
+  Bump()
+  io.Println(Count, REGION)
+  x = Count + 1
+  io.Println("${Count} ${x}", Primary.name)
+}`, counterModule},
+		"var Bump = mod1.Bump",
+		"func f(Count int) int {",
+		"return Count",
+		"io.Println(mod1.Count, mod1.REGION)",
+		"x := mod1.Count + 1",
+		`io.Println(io.Format("%d %d", mod1.Count, x), mod1.Primary.Name)`,
+	)
+}
+
+func TestImportedVariablesAreNotCopied(t *testing.T) {
+	code, err := compile(t, `from "/mod1.posh" import Count
+
+fn main() {
+  io.Println(Count)
+}`, counterModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(code, "var Count") {
+		t.Fatalf("expected Count to be used through mod1, got:\n%s", code)
+	}
+}
+
+func TestImportedVariableErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		err  string
+	}{
+		{"assignment", "fn main() {\n  Count = 3\n}", "cannot assign to Count, it is imported at 1:26"},
+		{"increment", "fn main() {\n  Count++\n}", "cannot assign to Count, it is imported at 1:26"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{`from "/mod1.posh" import Count` + "\n" + test.code, counterModule}, test.err)
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package rules
+
+import (
+	"go/ast"
+	"strings"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// Lambda is a function value: fn(x string) bool { ... } or x => x != "", the
+// types of the params and the result of the latter come from where it is
+// used, such as a param of a function type
+type Lambda struct {
+	types.BaseNode
+	// Keyword is fn, or => for arrow functions
+	Keyword types.Node  `json:"keyword"`
+	Params  *Parameters `json:"params"`
+	// Names are the params of arrow functions
+	Names      []types.Node  `json:"names"`
+	ReturnType types.Node    `json:"returnType"`
+	Body       *FunctionBody `json:"body"`
+	// Value is the result of an arrow function without a body: x => x * 2
+	Value   types.Node `json:"value"`
+	IsArrow bool       `json:"isArrow"`
+	// Expected is the function type that an arrow function is used as
+	Expected string `json:"expected"`
+	// Signature is the signature of the function once it is known
+	Signature types.Export `json:"signature"`
+}
+
+func (n *Lambda) GetPos() *types.Pos {
+	return n.Keyword.GetPos()
+}
+
+// isArrowFunction tells if an argument is an arrow function, which can only
+// be analyzed once the type of its param is known
+func isArrowFunction(node types.Node) bool {
+	if named, ok := node.(*NamedArg); ok {
+		node = named.Value
+	}
+
+	lambda, ok := node.(*Lambda)
+	return ok && lambda.IsArrow
+}
+
+// expectType gives an arrow function the function type it is used as
+func expectType(node types.Node, expected string) {
+	if lambda, ok := node.(*Lambda); ok && lambda.IsArrow {
+		lambda.Expected = expected
+	}
+}
+
+// signature returns the signature of the lambda, for arrow functions it
+// comes from the expected function type
+func (n *Lambda) signature(posh *types.PoshFile) (types.Export, bool) {
+	export := types.Export{
+		Type:   "void",
+		IsFunc: true,
+		Params: []types.Param{},
+	}
+
+	if !n.IsArrow {
+		if n.ReturnType != nil {
+			export.Type = n.ReturnType.GetImage()
+		}
+
+		for _, param := range n.Params.Params {
+			if param.Default != nil {
+				posh.Errorf(param.Default, "the params of a function value can not have default values")
+			}
+
+			export.Params = append(export.Params, types.Param{
+				Name:     param.Identifier.GetImage(),
+				Type:     param.ParamType.GetImage(),
+				Variadic: param.Variadic,
+			})
+		}
+
+		return export, true
+	}
+
+	if n.Expected == "" {
+		posh.Errorf(n, "the types of the params of this function are unknown, declare them: fn(x TYPE) TYPE { ... }")
+		return export, false
+	}
+
+	paramTypes, resultType, ok := funcTypes(n.Expected)
+	if !ok {
+		posh.Errorf(n, "cannot use a function as value of type %s", n.Expected)
+		return export, false
+	}
+
+	if len(paramTypes) != len(n.Names) {
+		posh.Errorf(n, "cannot use a function with %d params as %s", len(n.Names), n.Expected)
+		return export, false
+	}
+
+	export.Type = resultType
+	for i, name := range n.Names {
+		export.Params = append(export.Params, types.Param{
+			Name:     name.GetImage(),
+			Type:     strings.TrimPrefix(paramTypes[i], "..."),
+			Variadic: strings.HasPrefix(paramTypes[i], "..."),
+		})
+	}
+
+	return export, true
+}
+
+func (n *Lambda) StaticAnalysis(posh *types.PoshFile) {
+	signature, ok := n.signature(posh)
+	if !ok {
+		return
+	}
+
+	n.Signature = signature
+
+	// the body is a function of its own, break, continue and labels can not
+	// leave it
+	loops, labels := posh.Loops, posh.Labels
+	posh.Loops, posh.Labels = nil, map[string]bool{}
+
+	// the names of the enclosing scopes stay visible, Go closures capture
+	// them
+	posh.Environment.PushScope()
+	posh.Environment.Set(returnKey, signature.Type)
+	for _, param := range signature.Params {
+		if param.Variadic {
+			posh.Environment.Set(param.Name, "[]"+param.Type)
+		} else {
+			posh.Environment.Set(param.Name, param.Type)
+		}
+	}
+
+	if n.Body != nil {
+		n.Body.StaticAnalysis(posh)
+	} else {
+		expectType(n.Value, signature.Type)
+		n.Value.StaticAnalysis(posh)
+
+		if signature.Type != "void" {
+			checkType(posh, []types.Node{n.Value}, signature.Type, "result of type")
+		}
+	}
+
+	posh.Environment.PopScope()
+	posh.Loops, posh.Labels = loops, labels
+}
+
+func (n *Lambda) ToGoAst() ast.Node {
+	funcType := &ast.FuncType{
+		Params:  &ast.FieldList{},
+		Results: resultFields(n.Signature.Type),
+	}
+
+	for _, param := range n.Signature.Params {
+		paramType := typeExpr(param.Type)
+		if param.Variadic {
+			paramType = &ast.Ellipsis{Elt: paramType}
+		}
+
+		funcType.Params.List = append(funcType.Params.List, &ast.Field{
+			Names: []*ast.Ident{{Name: param.Name}},
+			Type:  paramType,
+		})
+	}
+
+	var body *ast.BlockStmt
+	if n.Body != nil {
+		body = n.Body.ToGoAst().(*ast.BlockStmt)
+	} else if stmt := n.Value.ToGoStatementAst(); n.Signature.Type == "void" && stmt != nil {
+		body = &ast.BlockStmt{List: []ast.Stmt{stmt}}
+	} else if n.Signature.Type == "void" {
+		body = &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: valueToGoAst(n.Value)}}}
+	} else {
+		body = &ast.BlockStmt{
+			List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{valueToGoAst(n.Value)}}},
+		}
+	}
+
+	return &ast.FuncLit{
+		Type: funcType,
+		Body: body,
+	}
+}
+
+func MatchLambda(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// fn PARAMETERS RESULT_TYPE? BODY
+
+	if nodes[offset].GetType() != "KEYWORD" || nodes[offset].GetImage() != "fn" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := Lambda{
+		BaseNode: types.BaseNode{
+			Type: "LAMBDA",
+		},
+		Keyword: nodes[offset],
+	}
+
+	res := MatchFunctionParams(nodes, offset+1)
+	if res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	node.Params = res.Node.(*Parameters)
+	offset = res.End
+
+	if res := MatchResultType(nodes, offset); res.End > res.Start {
+		node.ReturnType = res.Node
+		offset = res.End
+	}
+
+	res = MatchFunctionBody(nodes, offset)
+	if res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	node.Body = res.Node.(*FunctionBody)
+	return types.Result{Node: &node, Start: start, End: res.End}
+}
+
+func MatchArrowFunction(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// (IDENTIFIER | ( (IDENTIFIER (, IDENTIFIER)*)? )) => (EXPRESSION | BODY)
+
+	node := Lambda{
+		BaseNode: types.BaseNode{
+			Type: "LAMBDA",
+		},
+		IsArrow: true,
+	}
+
+	if nodes[offset].GetType() == "IDENTIFIER" {
+		node.Names = append(node.Names, nodes[offset])
+		offset++
+	} else if isPunctuator(nodes[offset], "(") {
+		offset++
+
+		for !isPunctuator(nodes[offset], ")") {
+			if nodes[offset].GetType() != "IDENTIFIER" {
+				return types.Result{FailedAt: &nodes[offset]}
+			}
+
+			node.Names = append(node.Names, nodes[offset])
+			offset++
+
+			if isPunctuator(nodes[offset], ",") {
+				offset++
+			} else if !isPunctuator(nodes[offset], ")") {
+				return types.Result{FailedAt: &nodes[offset]}
+			}
+		}
+
+		offset++
+	} else {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	if !isPunctuator(nodes[offset], "=>") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node.Keyword = nodes[offset]
+	offset++
+
+	if isPunctuator(nodes[offset], "{") {
+		res := MatchFunctionBody(nodes, offset)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node.Body = res.Node.(*FunctionBody)
+		return types.Result{Node: &node, Start: start, End: res.End}
+	}
+
+	res := MatchExpr(nodes, offset)
+	if res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	node.Value = res.Node
+	return types.Result{Node: &node, Start: start, End: res.End}
+}
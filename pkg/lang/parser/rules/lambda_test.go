@@ -0,0 +1,98 @@
+package rules
+
+import "testing"
+
+const applyFunc = `fn apply(f fn(int) int, x int) int {
+  return f(x)
+}
+`
+
+func TestFunctionValues(t *testing.T) {
+	expectGo(t, []string{applyFunc + `
+fn adder(n int) fn(int) int {
+  return x => x + n
+}
+
+fn each(xs []int, f fn(int, int)) void {
+  for i, x in xs {
+    f(i, x)
+  }
+}
+
+fn main() {
+  io.Println(apply(x => x * 2, 1))
+  io.Println(apply(f=adder(1), x=2))
+  total = 0
+  each([1, 2], (i, x) => {
+    total += x
+  })
+  var twice fn(int) int = x => x * 2
+  greet = fn(name string) {
+    io.Println(name)
+  }
+  io.Println(total, twice(2))
+  greet("posh")
+}`},
+		"func apply(f func(int) int, x int) int {",
+		"func adder(n int) func(int) int {",
+		"return func(x int) int {",
+		"return x + n",
+		"func each(xs []int, f func(int, int)) {",
+		"io.Println(apply(func(x int) int {",
+		"return x * 2",
+		"io.Println(apply(adder(1), 2))",
+		"each([]int{1, 2}, func(i int, x int) {",
+		"total += x",
+		"var twice func(int) int = func(x int) int {",
+		"greet := func(name string) {",
+	)
+}
+
+func TestFunctionValueErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"unknown params", "fn main() {\n  f = x => x\n}", "the types of the params of this function are unknown, declare them: fn(x TYPE) TYPE { ... }"},
+		{"param count", "fn main() {\n  io.Println(apply((a, b) => a, 1))\n}", "cannot use a function with 2 params as func(int) int"},
+		{"result type", "fn main() {\n  io.Println(apply(x => \"s\", 1))\n}", "cannot use string as result of type int"},
+		{"not a function type", "fn main() {\n  var s string = x => x\n}", "cannot use a function as value of type string"},
+		{"default", "fn main() {\n  g = fn(x int = 1) int { return x }\n}", "the params of a function value can not have default values"},
+		{"break", "fn main() {\n  for i in 0..3 {\n    h = fn() void {\n      break\n    }\n  }\n}", "break must be inside of a loop"},
+		{"signature", "fn main() {\n  apply(fn(x string) int { return 1 }, 2)\n}", "cannot use func(string) int as argument of type func(int) int"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{applyFunc + test.code}, test.error)
+		})
+	}
+}
+
+func TestFunctionValuesInRecords(t *testing.T) {
+	expectGo(t, []string{`type Check {
+  name string
+  run fn(string) bool
+}
+
+fn main() {
+  check = Check{name: "empty", run: s => s == ""}
+  io.Println(check.run(""))
+}`},
+		`check := Check{Name: "empty", Run: func(s string) bool {`,
+		`return s == ""`,
+		`io.Println(check.Run(""))`,
+	)
+}
+
+func TestImportedFunctionValues(t *testing.T) {
+	expectGo(t, []string{`from "/mod1.posh" import Double
+
+fn main() {
+  io.Println(Double(2))
+}`, `var Double fn(int) int = x => x * 2
+`},
+		"io.Println(mod1.Double(2))",
+	)
+}
@@ -0,0 +1,343 @@
+package rules
+
+import (
+	"go/ast"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// List is a list literal: [a, b] or []string{a, b}
+type List struct {
+	types.BaseNode
+	Open     types.Node   `json:"open"`
+	Declared *TypeNode    `json:"declared"`
+	Items    []types.Node `json:"items"`
+	// ItemType is the declared or inferred type of the items, it is empty
+	// when the types of the items are only known to Go
+	ItemType string `json:"itemType"`
+}
+
+func (n *List) GetPos() *types.Pos {
+	return n.Open.GetPos()
+}
+
+func (n *List) StaticAnalysis(posh *types.PoshFile) {
+	for _, item := range n.Items {
+		item.StaticAnalysis(posh)
+	}
+
+	if n.Declared != nil {
+		n.ItemType = elemType(n.Declared.Name)
+		checkType(posh, n.Items, n.ItemType, "list item")
+		return
+	}
+
+	if len(n.Items) == 0 {
+		posh.Errorf(n.Open, "the type of an empty list must be declared: []string{}")
+		return
+	}
+
+	// the items of [a, b] must have the same type
+	n.ItemType = typeOf(posh, n.Items[0])
+	for _, item := range n.Items[1:] {
+		itemType := typeOf(posh, item)
+		if itemType == "" || n.ItemType == "" {
+			n.ItemType = ""
+		} else if itemType != n.ItemType {
+			posh.Errorf(item, "list items must have the same type, found %s and %s", n.ItemType, itemType)
+		}
+	}
+
+	// Go infers the type of std.List(a, b)
+	if n.ItemType == "" {
+		posh.StdImports["std"] = true
+	}
+}
+
+func (n *List) ToGoAst() ast.Node {
+	items := []ast.Expr{}
+	for _, item := range n.Items {
+		items = append(items, valueToGoAst(item))
+	}
+
+	if n.ItemType == "" {
+		return &ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X:   &ast.Ident{Name: "std"},
+				Sel: &ast.Ident{Name: "List"},
+			},
+			Args: items,
+		}
+	}
+
+	return &ast.CompositeLit{
+		Type: typeExpr("[]" + n.ItemType),
+		Elts: items,
+	}
+}
+
+func MatchList(nodes []types.Node, offset int) types.Result {
+	start := offset
+	defer allowLiterals()()
+
+	// We are looking for one of the following:
+	// - TYPE { (EXPRESSION (, EXPRESSION)* ,?)? }
+	// - [ (EXPRESSION (, EXPRESSION)* ,?)? ]
+
+	if !isPunctuator(nodes[offset], "[") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := List{
+		BaseNode: types.BaseNode{
+			Type: "LIST",
+		},
+		Open: nodes[offset],
+	}
+
+	closing := "]"
+	if res := MatchType(nodes, offset); res.End > res.Start && isPunctuator(nodes[res.End], "{") {
+		node.Declared = res.Node.(*TypeNode)
+		offset = res.End
+		closing = "}"
+	}
+
+	offset++
+
+	for !isPunctuator(nodes[offset], closing) {
+		res := MatchExpr(nodes, offset)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node.Items = append(node.Items, res.Node)
+		offset = res.End
+
+		if isPunctuator(nodes[offset], ",") {
+			offset++
+		} else if !isPunctuator(nodes[offset], closing) {
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
+
+// Index is an item of a list or a map, or a byte of a string: xs[i]
+type Index struct {
+	types.BaseNode
+	Value types.Node `json:"value"`
+	Index types.Node `json:"index"`
+}
+
+func (n *Index) GetPos() *types.Pos {
+	return n.Value.GetPos()
+}
+
+func (n *Index) StaticAnalysis(posh *types.PoshFile) {
+	n.Value.StaticAnalysis(posh)
+	n.Index.StaticAnalysis(posh)
+
+	valueType := typeOf(posh, n.Value)
+	if key, value := mapTypes(valueType); value != "" {
+		checkType(posh, []types.Node{n.Index}, key, "map key")
+		return
+	}
+
+	if valueType != "" && valueType != "string" && elemType(valueType) == "" {
+		posh.Errorf(n.Value, "cannot index %s", valueType)
+	}
+
+	if indexType := typeOf(posh, n.Index); indexType != "" && indexType != "int" {
+		posh.Errorf(n.Index, "index must be int, not %s", indexType)
+	}
+}
+
+func (n *Index) ToGoAst() ast.Node {
+	return &ast.IndexExpr{
+		X:     valueToGoAst(n.Value),
+		Index: valueToGoAst(n.Index),
+	}
+}
+
+// Slice is a part of a list or a string: xs[low:high], both bounds are
+// optional
+type Slice struct {
+	types.BaseNode
+	Value types.Node `json:"value"`
+	Low   types.Node `json:"low"`
+	High  types.Node `json:"high"`
+}
+
+func (n *Slice) GetPos() *types.Pos {
+	return n.Value.GetPos()
+}
+
+func (n *Slice) StaticAnalysis(posh *types.PoshFile) {
+	n.Value.StaticAnalysis(posh)
+
+	valueType := typeOf(posh, n.Value)
+	if valueType != "" && valueType != "string" && elemType(valueType) == "" {
+		posh.Errorf(n.Value, "cannot slice %s", valueType)
+	}
+
+	for _, bound := range []types.Node{n.Low, n.High} {
+		if bound == nil {
+			continue
+		}
+
+		bound.StaticAnalysis(posh)
+
+		if boundType := typeOf(posh, bound); boundType != "" && boundType != "int" {
+			posh.Errorf(bound, "slice bounds must be int, not %s", boundType)
+		}
+	}
+}
+
+func (n *Slice) ToGoAst() ast.Node {
+	expr := &ast.SliceExpr{X: valueToGoAst(n.Value)}
+
+	if n.Low != nil {
+		expr.Low = valueToGoAst(n.Low)
+	}
+
+	if n.High != nil {
+		expr.High = valueToGoAst(n.High)
+	}
+
+	return expr
+}
+
+// matchIndex matches [INDEX] or [LOW:HIGH] after value
+func matchIndex(nodes []types.Node, offset int, value types.Node) types.Result {
+	start := offset
+	defer allowLiterals()()
+	offset++
+
+	var low types.Node
+	if !isPunctuator(nodes[offset], ":") {
+		// ranges are not allowed, xs[1..3] is ambiguous
+		res := matchBinary(nodes, offset, precLowest)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		low = res.Node
+		offset = res.End
+	}
+
+	if isPunctuator(nodes[offset], "]") && low != nil {
+		node := Index{
+			BaseNode: types.BaseNode{
+				Type: "INDEX",
+			},
+			Value: value,
+			Index: low,
+		}
+
+		return types.Result{Node: &node, Start: start, End: offset + 1}
+	}
+
+	if !isPunctuator(nodes[offset], ":") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	node := Slice{
+		BaseNode: types.BaseNode{
+			Type: "SLICE",
+		},
+		Value: value,
+		Low:   low,
+	}
+
+	if !isPunctuator(nodes[offset], "]") {
+		res := matchBinary(nodes, offset, precLowest)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node.High = res.Node
+		offset = res.End
+	}
+
+	if !isPunctuator(nodes[offset], "]") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
+
+// Membership tells if a value is in a list or a key is in a map: x in xs
+type Membership struct {
+	types.BaseNode
+	Lhs types.Node `json:"lhs"`
+	Op  types.Node `json:"op"`
+	Rhs types.Node `json:"rhs"`
+	// IsMap is set when looking for a key in a map
+	IsMap bool `json:"isMap"`
+}
+
+func (n *Membership) GetPos() *types.Pos {
+	return n.Lhs.GetPos()
+}
+
+func (n *Membership) StaticAnalysis(posh *types.PoshFile) {
+	n.Lhs.StaticAnalysis(posh)
+	n.Rhs.StaticAnalysis(posh)
+
+	posh.StdImports["std"] = true
+
+	rhsType := typeOf(posh, n.Rhs)
+	if rhsType == "" {
+		return
+	}
+
+	if key, value := mapTypes(rhsType); value != "" {
+		n.IsMap = true
+		checkType(posh, []types.Node{n.Lhs}, key, "map key")
+		return
+	}
+
+	itemType := elemType(rhsType)
+	if itemType == "" {
+		posh.Errorf(n.Op, "cannot use in with %s", rhsType)
+	} else if lhsType := typeOf(posh, n.Lhs); lhsType != "" && lhsType != itemType {
+		posh.Errorf(n.Lhs, "cannot look for %s in a list of %s", lhsType, itemType)
+	}
+}
+
+func (n *Membership) ToGoAst() ast.Node {
+	name := "Contains"
+	if n.IsMap {
+		name = "HasKey"
+	}
+
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   &ast.Ident{Name: "std"},
+			Sel: &ast.Ident{Name: name},
+		},
+		Args: []ast.Expr{valueToGoAst(n.Rhs), valueToGoAst(n.Lhs)},
+	}
+}
+
+// matchMembership matches in EXPRESSION, lhs is the already matched value
+func matchMembership(nodes []types.Node, offset int, lhs types.Node) types.Result {
+	res := matchBinary(nodes, offset+1, precComparison)
+	if res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	node := Membership{
+		BaseNode: types.BaseNode{
+			Type: "MEMBERSHIP",
+		},
+		Lhs: lhs,
+		Op:  nodes[offset],
+		Rhs: res.Node,
+	}
+
+	return types.Result{Node: &node, Start: offset, End: res.End}
+}
@@ -0,0 +1,65 @@
+package rules
+
+import "testing"
+
+func TestLists(t *testing.T) {
+	expectGo(t, []string{`fn main() {
+  files = ["a.txt", "b.txt"]
+  hosts = []string{}
+  ports = [80, 443]
+  first = files[0]
+  rest = files[1:]
+  head = ports[:1]
+  n = len(files)
+  hosts = append(hosts, "db")
+  if "a.txt" in files {
+    rm(-f, files[1:])
+  }
+  for i, file in append(files, "c.txt") {
+    io.Println(i, file)
+  }
+  for p in ports {
+    io.Println(p)
+  }
+  io.Println(first, rest, head, n, hosts)
+}`},
+		`files := []string{"a.txt", "b.txt"}`,
+		"hosts := []string{}",
+		"ports := []int{80, 443}",
+		"first := files[0]",
+		"rest := files[1:]",
+		"head := ports[:1]",
+		"n := len(files)",
+		`hosts = append(hosts, "db")`,
+		`if std.Contains(files, "a.txt") {`,
+		// lists are passed to commands as one argument per item
+		`exec.Run(rm(&exec.RunContext{}, exec.Args("-f", files[1:])...))`,
+		`for i, file := range append(files, "c.txt") {`,
+		"for _, p := range ports {",
+	)
+}
+
+func TestListErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"empty", "xs = []", "the type of an empty list must be declared: []string{}"},
+		{"mixed items", `xs = [1, "a"]`, "list items must have the same type, found int and string"},
+		{"index", `xs = [1]
+  io.Println(xs["a"])`, "index must be int, not string"},
+		{"index a number", "x = 1\n  io.Println(x[0])", "cannot index int"},
+		{"slice bounds", `xs = [1]
+  io.Println(xs["a":])`, "slice bounds must be int, not string"},
+		{"membership", `xs = [1]
+  io.Println("a" in xs)`, "cannot look for string in a list of int"},
+		{"append", "x = 1\n  x = append(x, 2)", "cannot append to int"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{"fn main() {\n  " + test.code + "\n}"}, test.error)
+		})
+	}
+}
@@ -1,43 +1,90 @@
 package rules
 
 import (
+	"fmt"
 	"go/ast"
 	"go/token"
 
 	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
 )
 
-// break and continue
+// break and continue, optionally with the label of the loop: break outer
 type ForControl struct {
 	types.BaseNode
-	Op string `json:"op"`
+	Op      string     `json:"op"`
+	Keyword types.Node `json:"keyword"`
+	Label   types.Node `json:"label"`
+	// GoLabel is the label of the loop in Go when the statement needs one
+	GoLabel string `json:"goLabel"`
+}
+
+func (n *ForControl) GetPos() *types.Pos {
+	return n.Keyword.GetPos()
+}
+
+func (n *ForControl) StaticAnalysis(posh *types.PoshFile) {
+	if block, ok := enclosingBlock(posh); ok && len(posh.Loops) == 0 {
+		posh.Errorf(n.Keyword, "%s can not leave a %s block", n.Op, block)
+		return
+	} else if len(posh.Loops) == 0 {
+		posh.Errorf(n.Keyword, "%s must be inside of a loop", n.Op)
+		return
+	}
+
+	loop := posh.Loops[len(posh.Loops)-1]
+	if n.Label != nil {
+		loop = nil
+		for i := len(posh.Loops) - 1; i >= 0; i-- {
+			if posh.Loops[i].Label == n.Label.GetImage() {
+				loop = posh.Loops[i]
+				break
+			}
+		}
+
+		if loop == nil {
+			posh.Errorf(n.Label, "there is no loop labeled %s around this %s", n.Label.GetImage(), n.Op)
+			return
+		}
+	} else if n.Op != "break" || loop.Switches == 0 {
+		return
+	}
+
+	// labeled, or a break that would only leave the switch of a match
+	loop.Used = true
+	n.GoLabel = loop.Label
 }
 
 func (n *ForControl) ToGoAst() ast.Node {
+	var label *ast.Ident
+	if n.GoLabel != "" {
+		label = &ast.Ident{Name: n.GoLabel}
+	}
+
 	if n.Op == "break" {
 		return &ast.BranchStmt{
-			Tok: token.BREAK,
+			Tok:   token.BREAK,
+			Label: label,
 		}
 	} else {
 		return &ast.BranchStmt{
-			Tok: token.CONTINUE,
+			Tok:   token.CONTINUE,
+			Label: label,
 		}
 	}
 }
 
+func (n *ForControl) ToGoStatementAst() ast.Stmt {
+	return n.ToGoAst().(ast.Stmt)
+}
+
 type ForBody struct {
 	types.BaseNode
 	Content []types.Node `json:"content"`
 }
 
 func (n *ForBody) ToGoAst() ast.Node {
-	body := []ast.Stmt{}
-	for _, content := range n.Content {
-		body = append(body, content.ToGoStatementAst())
-	}
-
 	return &ast.BlockStmt{
-		List: body,
+		List: statements(n.Content),
 	}
 }
 
@@ -51,6 +98,80 @@ func isForControl(s string) bool {
 	return s == "break" || s == "continue"
 }
 
+func MatchForControl(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// (BREAK | CONTINUE) IDENTIFIER?
+
+	if nodes[offset].GetType() != "KEYWORD" || !isForControl(nodes[offset].GetImage()) {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := ForControl{
+		BaseNode: types.BaseNode{
+			Type: "FOR_CONTROL",
+		},
+		Op:      nodes[offset].GetImage(),
+		Keyword: nodes[offset],
+	}
+
+	offset++
+
+	// the label has to be on the same line, the next line is a new statement
+	if nodes[offset].GetType() == "IDENTIFIER" && nodes[offset].GetPos().Line == node.Keyword.GetPos().Line {
+		node.Label = nodes[offset]
+		offset++
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset}
+}
+
+// matchLoopLabel matches the label of a loop: outer: for x in xs { ... }, it
+// returns the offset of the loop
+func matchLoopLabel(nodes []types.Node, offset int) (types.Node, int) {
+	if nodes[offset].GetType() == "IDENTIFIER" && isPunctuator(nodes[offset+1], ":") && isLoopKeyword(nodes[offset+2]) {
+		return nodes[offset], offset + 2
+	}
+
+	return nil, offset
+}
+
+// enterLoop adds a loop to the loops around the code that is being analyzed,
+// loops without a label get one in case a break inside of a match needs it
+func enterLoop(posh *types.PoshFile, keyword types.Node, label types.Node) *types.Loop {
+	loop := &types.Loop{Label: fmt.Sprintf("__loop%d", keyword.GetPos().Offset)}
+
+	if label != nil {
+		if posh.Labels[label.GetImage()] {
+			posh.Errorf(label, "label %s is already used in this function", label.GetImage())
+		}
+
+		posh.Labels[label.GetImage()] = true
+		loop.Label = label.GetImage()
+	}
+
+	posh.Loops = append(posh.Loops, loop)
+	return loop
+}
+
+func exitLoop(posh *types.PoshFile) {
+	posh.Loops = posh.Loops[:len(posh.Loops)-1]
+}
+
+// labeled adds the label of the loop to its statement, Go does not allow
+// labels that are not used
+func labeled(loop *types.Loop, stmt ast.Stmt) ast.Stmt {
+	if loop == nil || !loop.Used {
+		return stmt
+	}
+
+	return &ast.LabeledStmt{
+		Label: &ast.Ident{Name: loop.Label},
+		Stmt:  stmt,
+	}
+}
+
 func MatchForBody(nodes []types.Node, offset int) types.Result {
 	start := offset
 
@@ -58,6 +179,7 @@ func MatchForBody(nodes []types.Node, offset int) types.Result {
 		return types.Result{FailedAt: &nodes[offset]}
 	}
 
+	defer allowLiterals()()
 	offset++
 
 	node := ForBody{
@@ -72,29 +194,9 @@ func MatchForBody(nodes []types.Node, offset int) types.Result {
 			break
 		}
 
-		if res := MatchAssignment(nodes, offset); res.End > res.Start {
-			node.Content = append(node.Content, res.Node)
-			offset = res.End
-		} else if res := MatchFunctionCall(nodes, offset); res.End > res.Start {
-			node.Content = append(node.Content, res.Node)
-			offset = res.End
-		} else if res := MatchReturnStatement(nodes, offset); res.End > res.Start {
-			node.Content = append(node.Content, res.Node)
-			offset = res.End
-		} else if res := MatchIfStatement(nodes, offset); res.End > res.Start {
+		if res := MatchStatement(nodes, offset); res.End > res.Start {
 			node.Content = append(node.Content, res.Node)
 			offset = res.End
-		} else if res := MatchForLoop(nodes, offset); res.End > res.Start {
-			node.Content = append(node.Content, res.Node)
-			offset = res.End
-		} else if nodes[offset].GetType() == "KEYWORD" && isForControl(nodes[offset].GetImage()) {
-			node.Content = append(node.Content, &ForControl{
-				BaseNode: types.BaseNode{
-					Type: "FOR_CONTROL",
-				},
-				Op: nodes[offset].GetImage(),
-			})
-			offset++
 		} else {
 			return types.Result{FailedAt: &nodes[offset]}
 		}
@@ -105,14 +207,50 @@ func MatchForBody(nodes []types.Node, offset int) types.Result {
 
 type ForLoop struct {
 	types.BaseNode
+	Keyword   types.Node   `json:"keyword"`
+	Label     types.Node   `json:"label"`
 	Variables []types.Node `json:"variables"`
 	Iterable  types.Node   `json:"iterable"`
 	Body      *ForBody     `json:"body"`
+	// ItemType is the type of the items when iterating over a list
+	ItemType string      `json:"itemType"`
+	Loop     *types.Loop `json:"loop"`
+}
+
+func (n *ForLoop) GetPos() *types.Pos {
+	return n.Keyword.GetPos()
 }
 
 func (n *ForLoop) StaticAnalysis(posh *types.PoshFile) {
 	n.Iterable.StaticAnalysis(posh)
+	n.ItemType = elemType(typeOf(posh, n.Iterable))
+
+	posh.Environment.PushScope()
+
+	// for x in xs goes over the items, for i, x in xs over the indexes too,
+	// for k, v in m goes over the keys and the values
+	iterableType := typeOf(posh, n.Iterable)
+	variableTypes := []string{"unknown", "unknown"}
+	if _, ok := n.Iterable.(*Range); ok {
+		variableTypes[0] = "int"
+	} else if n.ItemType != "" && len(n.Variables) == 1 {
+		variableTypes[0] = n.ItemType
+	} else if n.ItemType != "" {
+		variableTypes = []string{"int", n.ItemType}
+	} else if key, value := mapTypes(iterableType); value != "" {
+		variableTypes = []string{key, value}
+	} else if key, value := seqTypes(iterableType); value != "" {
+		variableTypes = []string{key, value}
+	}
+
+	for i, variable := range n.Variables {
+		posh.Environment.Set(variable.GetImage(), variableTypes[i])
+	}
+
+	n.Loop = enterLoop(posh, n.Keyword, n.Label)
 	n.Body.StaticAnalysis(posh)
+	exitLoop(posh)
+	posh.Environment.PopScope()
 }
 
 func (n *ForLoop) ToGoStatementAst() ast.Stmt {
@@ -123,40 +261,49 @@ func (n *ForLoop) ToGoStatementAst() ast.Stmt {
 	var valueVar ast.Expr
 	if len(n.Variables) > 1 {
 		valueVar = &ast.Ident{Name: n.Variables[1].GetImage()}
+	} else if n.ItemType != "" {
+		// the only variable of a loop over a list is the item
+		valueVar = keyVar
+		keyVar = &ast.Ident{Name: "_"}
 	}
 
-	iterableExpr := n.Iterable.ToGoAst().(ast.Expr)
+	iterableExpr := valueToGoAst(n.Iterable)
 	bodyStmt := n.Body.ToGoAst().(*ast.BlockStmt)
 
 	// Create the range statement
-	return &ast.RangeStmt{
+	return labeled(n.Loop, &ast.RangeStmt{
 		Key:   keyVar,
 		Value: valueVar,
 		Tok:   token.DEFINE,
 		X:     iterableExpr,
 		Body:  bodyStmt,
-	}
+	})
 }
 
 func MatchForLoop(nodes []types.Node, offset int) types.Result {
 	start := offset
 
 	// We are looking for the following:
-	// FOR IDENTIFIER (, IDENTIFIER)? IN EXPRESSION BODY
+	// (IDENTIFIER :)? FOR IDENTIFIER (, IDENTIFIER)? IN EXPRESSION BODY
 
-	// try to match IF
+	label, offset := matchLoopLabel(nodes, offset)
+
+	// try to match FOR
 	if nodes[offset].GetType() != "KEYWORD" || nodes[offset].GetImage() != "for" {
 		return types.Result{FailedAt: &nodes[offset]}
 	}
-	offset++
 
 	// create the node
 	node := ForLoop{
 		BaseNode: types.BaseNode{
 			Type: "FOR",
 		},
+		Keyword: nodes[offset],
+		Label:   label,
 	}
 
+	offset++
+
 	// try to match IDENTIFIER
 	if nodes[offset].GetType() != "IDENTIFIER" {
 		return types.Result{FailedAt: &nodes[offset]}
@@ -183,8 +330,8 @@ func MatchForLoop(nodes []types.Node, offset int) types.Result {
 	offset++
 
 	// try to match EXPRESSION
-	if res := MatchSimpleExpression(nodes, offset); res.End <= res.Start {
-		return types.Result{FailedAt: &nodes[offset]}
+	if res := matchHeader(nodes, offset); res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
 	} else {
 		offset = res.End
 		node.Iterable = res.Node
@@ -200,3 +347,88 @@ func MatchForLoop(nodes []types.Node, offset int) types.Result {
 
 	return types.Result{Node: &node, Start: start, End: offset}
 }
+
+// WhileLoop runs its body as long as the condition holds, loop { ... } has no
+// condition and runs until a break
+type WhileLoop struct {
+	types.BaseNode
+	Keyword   types.Node  `json:"keyword"`
+	Label     types.Node  `json:"label"`
+	Condition types.Node  `json:"condition"`
+	Body      *ForBody    `json:"body"`
+	Loop      *types.Loop `json:"loop"`
+}
+
+func (n *WhileLoop) GetPos() *types.Pos {
+	return n.Keyword.GetPos()
+}
+
+func (n *WhileLoop) StaticAnalysis(posh *types.PoshFile) {
+	if n.Condition != nil {
+		n.Condition.StaticAnalysis(posh)
+		checkType(posh, []types.Node{n.Condition}, "bool", "condition of type")
+	}
+
+	posh.Environment.PushScope()
+	n.Loop = enterLoop(posh, n.Keyword, n.Label)
+	n.Body.StaticAnalysis(posh)
+	exitLoop(posh)
+	posh.Environment.PopScope()
+}
+
+func (n *WhileLoop) ToGoStatementAst() ast.Stmt {
+	// while cond { ... } is for cond { ... } and loop { ... } is for { ... }
+	stmt := &ast.ForStmt{
+		Body: n.Body.ToGoAst().(*ast.BlockStmt),
+	}
+
+	if n.Condition != nil {
+		stmt.Cond = valueToGoAst(n.Condition)
+	}
+
+	return labeled(n.Loop, stmt)
+}
+
+func MatchWhileLoop(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for one of the following:
+	// - (IDENTIFIER :)? WHILE EXPRESSION BODY
+	// - (IDENTIFIER :)? LOOP BODY
+
+	label, offset := matchLoopLabel(nodes, offset)
+
+	if nodes[offset].GetType() != "KEYWORD" || (nodes[offset].GetImage() != "while" && nodes[offset].GetImage() != "loop") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := WhileLoop{
+		BaseNode: types.BaseNode{
+			Type: "WHILE",
+		},
+		Keyword: nodes[offset],
+		Label:   label,
+	}
+
+	offset++
+
+	// try to match the condition
+	if node.Keyword.GetImage() == "while" {
+		if res := matchHeader(nodes, offset); res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		} else {
+			offset = res.End
+			node.Condition = res.Node
+		}
+	}
+
+	// try to match BODY
+	if res := MatchForBody(nodes, offset); res.End <= res.Start {
+		return types.Result{FailedAt: &nodes[offset]}
+	} else {
+		offset = res.End
+		node.Body = res.Node.(*ForBody)
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset}
+}
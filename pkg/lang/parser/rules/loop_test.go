@@ -0,0 +1,105 @@
+package rules
+
+import "testing"
+
+func TestWhileLoops(t *testing.T) {
+	expectGo(t, []string{`fn main(status string) {
+  while cat(status) != "ready\n" {
+    sleep(1)
+  }
+  n = 0
+  loop {
+    n++
+    continue
+  }
+  while n > 0 {
+    n--
+    break
+  }
+}`},
+		// the condition is evaluated before every iteration
+		`for cat(&exec.RunContext{}, status).Wait().ToString() != "ready\n" {`,
+		"std.Sleep(1)",
+		"for {",
+		"n++",
+		"continue",
+		"for n > 0 {",
+		"n--",
+		"break",
+	)
+}
+
+func TestWhileLoopErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"condition", "while 1 {\n  }", "cannot use int as condition of type bool"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{"fn main() {\n  " + test.code + "\n}"}, test.error)
+		})
+	}
+}
+
+func TestLoopControl(t *testing.T) {
+	expectGo(t, []string{`fn main() {
+  for x in [1, 5] {
+    if x > 3 {
+      break
+    } else {
+      continue
+    }
+  }
+  outer: for i in 0..3 {
+    inner: while true {
+      if i == 1 {
+        continue outer
+      }
+      break inner
+    }
+    for j in 0..3 {
+      if i * j == 2 {
+        break outer
+      }
+    }
+  }
+}`},
+		"for _, x := range []int{1, 5} {",
+		"if x > 3 {",
+		"break",
+		"continue",
+		// only loops that are left or continued by name have a label in Go
+		"outer:",
+		"for i := range std.LazyRange(0, 1, 3) {",
+		"inner:",
+		"for true {",
+		"continue outer",
+		"break inner",
+		"break outer",
+	)
+}
+
+func TestLoopControlErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"break outside", "break", "break must be inside of a loop"},
+		{"continue in if", "if true {\n    continue\n  }", "continue must be inside of a loop"},
+		{"unknown label", "for i in 0..3 {\n    break outer\n  }", "there is no loop labeled outer around this break"},
+		{"label after loop", "outer: loop {\n    break\n  }\n  for i in 0..3 {\n    break outer\n  }", "there is no loop labeled outer around this break"},
+		{"duplicate label", "l: loop {\n    break\n  }\n  l: loop {\n    break\n  }", "label l is already used in this function"},
+		{"function value", "for i in 0..3 {\n    f = fn() {\n      break\n    }\n    f()\n  }", "break must be inside of a loop"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{"fn main() {\n  " + test.code + "\n}"}, test.error)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"go/ast"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// Map is a map literal: {"prod": "eu-1"} or map[string]string{"prod": "eu-1"}
+type Map struct {
+	types.BaseNode
+	Open     types.Node   `json:"open"`
+	Declared *TypeNode    `json:"declared"`
+	Keys     []types.Node `json:"keys"`
+	Values   []types.Node `json:"values"`
+	// KeyType and ValueType are declared or inferred, so the keys and the
+	// values of untyped literals must have known types
+	KeyType   string `json:"keyType"`
+	ValueType string `json:"valueType"`
+}
+
+func (n *Map) GetPos() *types.Pos {
+	return n.Open.GetPos()
+}
+
+// inferType returns the type shared by the nodes, it reports the nodes of a
+// different type
+func inferType(posh *types.PoshFile, nodes []types.Node, what string) string {
+	inferred := ""
+	for _, node := range nodes {
+		nodeType := typeOf(posh, node)
+		if nodeType == "" {
+			posh.Errorf(node, "the type of the map %s is unknown, declare the type of the map: map[string]string{}", what)
+			return ""
+		}
+
+		if inferred == "" {
+			inferred = nodeType
+		} else if nodeType != inferred {
+			posh.Errorf(node, "map %ss must have the same type, found %s and %s", what, inferred, nodeType)
+		}
+	}
+
+	return inferred
+}
+
+func (n *Map) StaticAnalysis(posh *types.PoshFile) {
+	for i := range n.Keys {
+		n.Keys[i].StaticAnalysis(posh)
+		n.Values[i].StaticAnalysis(posh)
+	}
+
+	if n.Declared != nil {
+		n.KeyType, n.ValueType = mapTypes(n.Declared.Name)
+		checkType(posh, n.Keys, n.KeyType, "map key")
+		checkType(posh, n.Values, n.ValueType, "map value")
+		return
+	}
+
+	if len(n.Keys) == 0 {
+		posh.Errorf(n.Open, "the type of an empty map must be declared: map[string]string{}")
+		return
+	}
+
+	n.KeyType = inferType(posh, n.Keys, "key")
+	n.ValueType = inferType(posh, n.Values, "value")
+}
+
+func (n *Map) ToGoAst() ast.Node {
+	elts := []ast.Expr{}
+	for i := range n.Keys {
+		elts = append(elts, &ast.KeyValueExpr{
+			Key:   valueToGoAst(n.Keys[i]),
+			Value: valueToGoAst(n.Values[i]),
+		})
+	}
+
+	return &ast.CompositeLit{
+		Type: typeExpr("map[" + n.KeyType + "]" + n.ValueType),
+		Elts: elts,
+	}
+}
+
+func MatchMap(nodes []types.Node, offset int) types.Result {
+	start := offset
+	defer allowLiterals()()
+
+	// We are looking for one of the following:
+	// - TYPE { (EXPRESSION : EXPRESSION (, EXPRESSION : EXPRESSION)* ,?)? }
+	// - { (EXPRESSION : EXPRESSION (, EXPRESSION : EXPRESSION)* ,?)? }
+
+	node := Map{
+		BaseNode: types.BaseNode{
+			Type: "MAP",
+		},
+		Open: nodes[offset],
+	}
+
+	if res := MatchType(nodes, offset); res.End > res.Start && isPunctuator(nodes[res.End], "{") {
+		if _, value := mapTypes(res.Node.GetImage()); value == "" {
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+
+		node.Declared = res.Node.(*TypeNode)
+		offset = res.End
+	}
+
+	if !isPunctuator(nodes[offset], "{") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	for !isPunctuator(nodes[offset], "}") {
+		key := MatchExpr(nodes, offset)
+		if key.End <= key.Start {
+			return types.Result{FailedAt: key.FailedAt}
+		}
+
+		if !isPunctuator(nodes[key.End], ":") {
+			return types.Result{FailedAt: &nodes[key.End]}
+		}
+
+		value := MatchExpr(nodes, key.End+1)
+		if value.End <= value.Start {
+			return types.Result{FailedAt: value.FailedAt}
+		}
+
+		node.Keys = append(node.Keys, key.Node)
+		node.Values = append(node.Values, value.Node)
+		offset = value.End
+
+		if isPunctuator(nodes[offset], ",") {
+			offset++
+		} else if !isPunctuator(nodes[offset], "}") {
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
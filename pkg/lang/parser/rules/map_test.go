@@ -0,0 +1,56 @@
+package rules
+
+import "testing"
+
+func TestMaps(t *testing.T) {
+	expectGo(t, []string{`fn main() {
+  regions = {"prod": "eu-1", "dev": "local"}
+  counts = map[string]int{}
+  for _, region in sorted(regions) {
+    if region in counts {
+      counts[region]++
+    } else {
+      counts[region] = 1
+    }
+  }
+  for k, v in regions {
+    io.Println(k, v)
+  }
+  delete(regions, "dev")
+  r = regions["prod"]
+  io.Println(counts, r)
+}`},
+		`regions := map[string]string{"prod": "eu-1", "dev": "local"}`,
+		"counts := map[string]int{}",
+		// sorted goes over the map in the order of its keys
+		"for _, region := range std.Sorted(regions) {",
+		"if std.HasKey(counts, region) {",
+		"counts[region]++",
+		"counts[region] = 1",
+		"for k, v := range regions {",
+		`delete(regions, "dev")`,
+		`r := regions["prod"]`,
+	)
+}
+
+func TestMapErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"empty", "m = {}", "the type of an empty map must be declared: map[string]string{}"},
+		{"mixed keys", `m = {"a": 1, 2: 2}`, "map keys must have the same type, found string and int"},
+		{"mixed values", `m = {"a": 1, "b": "c"}`, "map values must have the same type, found int and string"},
+		{"value type", "m = map[string]int{}\n  m[\"a\"] = \"x\"", "cannot use string as map value int"},
+		{"key type", "m = map[string]int{}\n  io.Println(m[1])", "cannot use int as map key string"},
+		{"delete", "x = 1\n  delete(x, 1)", "cannot delete from int"},
+		{"sorted", "x = 1\n  io.Println(sorted(x))", "cannot use sorted with int"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{"fn main() {\n  " + test.code + "\n}"}, test.error)
+		})
+	}
+}
@@ -0,0 +1,470 @@
+package rules
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// Regex is a regular expression pattern of a match arm: re`^v[0-9]+$`
+type Regex struct {
+	types.BaseNode
+	Pattern types.Node `json:"pattern"`
+	// Variable is the compiled expression, regular expressions are compiled
+	// once when the program starts
+	Variable string `json:"variable"`
+}
+
+func (n *Regex) GetPos() *types.Pos {
+	return n.Pattern.GetPos()
+}
+
+func (n *Regex) StaticAnalysis(posh *types.PoshFile) {
+	pattern := n.Pattern.(*types.TokenNode).Value
+	if _, err := regexp.Compile(pattern); err != nil {
+		posh.Errorf(n.Pattern, "invalid regular expression: %s", err)
+		return
+	}
+
+	// var __regex0 = regexp.MustCompile(pattern)
+	n.Variable = fmt.Sprintf("__regex%d", len(posh.TopLevelAssignments))
+	posh.StdImports["regexp"] = true
+	posh.TopLevelAssignments = append(posh.TopLevelAssignments, &ast.ValueSpec{
+		Names: []*ast.Ident{{Name: n.Variable}},
+		Values: []ast.Expr{
+			&ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "regexp"},
+					Sel: &ast.Ident{Name: "MustCompile"},
+				},
+				Args: []ast.Expr{
+					&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(pattern)},
+				},
+			},
+		},
+	})
+}
+
+type MatchArm struct {
+	types.BaseNode
+	// Patterns are empty for the default arm: _ => value
+	Patterns []types.Node `json:"patterns"`
+	Arrow    types.Node   `json:"arrow"`
+	// Value is an expression or, when the match is a statement, a block
+	Value types.Node `json:"value"`
+}
+
+// Match picks the value of the first arm with a pattern that matches the
+// subject: match env { Env.dev => "local", _ => "eu-1" }
+type Match struct {
+	types.BaseNode
+	Keyword types.Node  `json:"keyword"`
+	Subject types.Node  `json:"subject"`
+	Arms    []*MatchArm `json:"arms"`
+	// IsStatement is set when the value of the match is not used, its arms
+	// can then be blocks
+	IsStatement bool `json:"isStatement"`
+	// ResultType is the type of the values of the arms
+	ResultType string `json:"resultType"`
+}
+
+func (n *Match) GetPos() *types.Pos {
+	return n.Keyword.GetPos()
+}
+
+func (n *Match) hasDefault() bool {
+	return len(n.Arms) > 0 && len(n.Arms[len(n.Arms)-1].Patterns) == 0
+}
+
+func (n *Match) hasRegex() bool {
+	for _, arm := range n.Arms {
+		for _, pattern := range arm.Patterns {
+			if _, ok := pattern.(*Regex); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (n *Match) StaticAnalysis(posh *types.PoshFile) {
+	n.Subject.StaticAnalysis(posh)
+	subjectType := typeOf(posh, n.Subject)
+
+	// a match statement is a switch in Go, see ForControl
+	var loop *types.Loop
+	if n.IsStatement && len(posh.Loops) > 0 {
+		loop = posh.Loops[len(posh.Loops)-1]
+		loop.Switches++
+	}
+
+	covered := map[string]bool{}
+	// Go rejects the same constant in two cases of a switch
+	matched := map[string]types.Node{}
+	for i, arm := range n.Arms {
+		if len(arm.Patterns) == 0 && i != len(n.Arms)-1 {
+			posh.Errorf(arm.Arrow, "the default arm must be the last one")
+		}
+
+		for _, pattern := range arm.Patterns {
+			pattern.StaticAnalysis(posh)
+
+			if _, ok := pattern.(*Regex); ok {
+				if subjectType != "" && subjectType != "string" {
+					posh.Errorf(pattern, "cannot match %s with a regular expression", subjectType)
+				}
+				continue
+			}
+
+			checkType(posh, []types.Node{pattern}, subjectType, "pattern of type")
+
+			if value := constantValue(pattern); value != "" {
+				if first, ok := matched[value]; ok {
+					posh.Errorf(pattern, "%s is already matched at %s", value, first.GetPos())
+				}
+				matched[value] = pattern
+			}
+
+			if value, ok := pattern.(*DotNotation); ok && value.Enum != "" {
+				covered[value.Accessors[1].GetImage()] = true
+			}
+		}
+
+		if _, ok := arm.Value.(*FunctionBody); ok {
+			if !n.IsStatement {
+				posh.Errorf(arm.Arrow, "the arms of a match that has a value can not be blocks")
+			}
+
+			posh.Environment.PushScope()
+			arm.Value.StaticAnalysis(posh)
+			posh.Environment.PopScope()
+		} else {
+			arm.Value.StaticAnalysis(posh)
+		}
+	}
+
+	if loop != nil {
+		loop.Switches--
+	}
+
+	// the arms of a match on an enum must cover all of its values
+	if enum, ok := posh.Enums[subjectType]; ok && !n.hasDefault() {
+		missing := []string{}
+		for _, value := range enum.Values {
+			if !covered[value] {
+				missing = append(missing, value)
+			}
+		}
+
+		sort.Strings(missing)
+		if len(missing) > 0 {
+			posh.Errorf(n.Keyword, "match on %s does not cover %s, add their arms or a default arm: _ => ...", subjectType, strings.Join(missing, ", "))
+		}
+	} else if !ok && !n.IsStatement && !n.hasDefault() {
+		posh.Errorf(n.Keyword, "match needs a default arm: _ => ...")
+	}
+
+	if n.IsStatement {
+		return
+	}
+
+	for _, arm := range n.Arms {
+		armType := typeOf(posh, arm.Value)
+		if armType == "" {
+			continue
+		}
+
+		if n.ResultType == "" {
+			n.ResultType = armType
+		} else if armType != n.ResultType {
+			posh.Errorf(arm.Value, "the arms of a match must have the same type, found %s and %s", n.ResultType, armType)
+		}
+	}
+
+	if n.ResultType == "" {
+		posh.Errorf(n.Keyword, "the type of the arms of the match is unknown")
+	}
+}
+
+// constantValue returns the value of a pattern that is a constant, in the same
+// form for the constants that are equal in Go: 0x10 and 16, or "" when the
+// pattern is not a constant
+func constantValue(pattern types.Node) string {
+	switch pattern := pattern.(type) {
+	case *SimpleExpression:
+		return constantValue(pattern.Value)
+	case *Numeric:
+		return constantValue(pattern.Value)
+	case *Boolean:
+		return constantValue(pattern.Value)
+	case *types.TokenNode:
+		switch pattern.Type {
+		case "STRING":
+			return strconv.Quote(pattern.Value)
+		case "BOOLEAN":
+			return pattern.Image
+		case "INTEGER":
+			if value, err := strconv.ParseInt(pattern.Image, 0, 64); err == nil {
+				return strconv.FormatInt(value, 10)
+			}
+		case "FLOAT":
+			if value, err := strconv.ParseFloat(pattern.Image, 64); err == nil {
+				return strconv.FormatFloat(value, 'g', -1, 64)
+			}
+		}
+	case *DotNotation:
+		if pattern.Enum != "" {
+			return pattern.Enum + "." + pattern.Accessors[1].GetImage()
+		}
+	case *Unary:
+		if value := constantValue(pattern.Value); value != "" && value[0] != '-' {
+			return "-" + value
+		}
+	}
+
+	return ""
+}
+
+// switchStmt returns the switch of the match, body returns the statements of
+// an arm
+func (n *Match) switchStmt(body func(arm *MatchArm) []ast.Stmt) *ast.SwitchStmt {
+	stmt := &ast.SwitchStmt{Body: &ast.BlockStmt{}}
+
+	// A switch on the subject is enough unless there are regular expressions:
+	// switch __subject := subject; { case __regex0.MatchString(__subject): }
+	subject := valueToGoAst(n.Subject)
+	if n.hasRegex() {
+		stmt.Init = &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent("__subject")},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{subject},
+		}
+		subject = ast.NewIdent("__subject")
+	} else {
+		stmt.Tag = subject
+	}
+
+	for _, arm := range n.Arms {
+		clause := &ast.CaseClause{Body: body(arm)}
+
+		var cond ast.Expr
+		for _, pattern := range arm.Patterns {
+			var expr ast.Expr
+			if regex, ok := pattern.(*Regex); ok {
+				expr = &ast.CallExpr{
+					Fun: &ast.SelectorExpr{
+						X:   ast.NewIdent(regex.Variable),
+						Sel: ast.NewIdent("MatchString"),
+					},
+					Args: []ast.Expr{subject},
+				}
+			} else {
+				expr = valueToGoAst(pattern)
+			}
+
+			if !n.hasRegex() {
+				clause.List = append(clause.List, expr)
+				continue
+			}
+
+			if _, ok := pattern.(*Regex); !ok {
+				expr = &ast.BinaryExpr{X: subject, Op: token.EQL, Y: expr}
+			}
+
+			if cond == nil {
+				cond = expr
+			} else {
+				cond = binaryExpr(cond, token.LOR, expr)
+			}
+		}
+
+		if cond != nil {
+			clause.List = []ast.Expr{cond}
+		}
+
+		stmt.Body.List = append(stmt.Body.List, clause)
+	}
+
+	return stmt
+}
+
+func (n *Match) ToGoAst() ast.Node {
+	// func() T { switch subject { case pattern: return value } }()
+	stmt := n.switchStmt(func(arm *MatchArm) []ast.Stmt {
+		return []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{valueToGoAst(arm.Value)}}}
+	})
+
+	stmts := []ast.Stmt{stmt}
+
+	// Go does not know that the arms cover all the values of the enum
+	if !n.hasDefault() {
+		stmts = append(stmts, &ast.ExprStmt{
+			X: &ast.CallExpr{
+				Fun:  ast.NewIdent("panic"),
+				Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: `"unreachable"`}},
+			},
+		})
+	}
+
+	return &ast.CallExpr{
+		Fun: &ast.FuncLit{
+			Type: &ast.FuncType{
+				Params:  &ast.FieldList{},
+				Results: &ast.FieldList{List: []*ast.Field{{Type: typeExpr(n.ResultType)}}},
+			},
+			Body: &ast.BlockStmt{List: stmts},
+		},
+	}
+}
+
+func (n *Match) ToGoStatementAst() ast.Stmt {
+	return n.switchStmt(func(arm *MatchArm) []ast.Stmt {
+		if body, ok := arm.Value.(*FunctionBody); ok {
+			return body.ToGoAst().(*ast.BlockStmt).List
+		}
+
+		return []ast.Stmt{arm.Value.ToGoStatementAst()}
+	})
+}
+
+func matchPattern(nodes []types.Node, offset int) types.Result {
+	// We are looking for one of the following:
+	// - re STRING
+	// - EXPRESSION
+
+	if nodes[offset].GetType() == "IDENTIFIER" && nodes[offset].GetImage() == "re" && nodes[offset+1].GetType() == "STRING" {
+		node := Regex{
+			BaseNode: types.BaseNode{
+				Type: "REGEX",
+			},
+			Pattern: nodes[offset+1],
+		}
+
+		return types.Result{Node: &node, Start: offset, End: offset + 2}
+	}
+
+	return matchBinary(nodes, offset, precLowest)
+}
+
+func matchArm(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// (_ | PATTERN (, PATTERN)*) => (EXPRESSION | BODY)
+
+	node := MatchArm{
+		BaseNode: types.BaseNode{
+			Type: "MATCH_ARM",
+		},
+	}
+
+	if nodes[offset].GetImage() == "_" && isPunctuator(nodes[offset+1], "=>") {
+		offset++
+	} else {
+		for {
+			res := matchPattern(nodes, offset)
+			if res.End <= res.Start {
+				return types.Result{FailedAt: res.FailedAt}
+			}
+
+			node.Patterns = append(node.Patterns, res.Node)
+			offset = res.End
+
+			if !isPunctuator(nodes[offset], ",") {
+				break
+			}
+
+			offset++
+		}
+	}
+
+	if !isPunctuator(nodes[offset], "=>") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node.Arrow = nodes[offset]
+	offset++
+
+	if isPunctuator(nodes[offset], "{") {
+		if res := MatchFunctionBody(nodes, offset); res.End > res.Start {
+			node.Value = res.Node
+			return types.Result{Node: &node, Start: start, End: res.End}
+		}
+	}
+
+	res := MatchExpr(nodes, offset)
+	if res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	node.Value = res.Node
+	return types.Result{Node: &node, Start: start, End: res.End}
+}
+
+func MatchMatch(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// match EXPRESSION { (ARM ,?)* }
+
+	// match is not a keyword, match(x) is still a call
+	if nodes[offset].GetType() != "IDENTIFIER" || nodes[offset].GetImage() != "match" ||
+		isPunctuator(nodes[offset+1], "(") || isPunctuator(nodes[offset+1], ".") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := Match{
+		BaseNode: types.BaseNode{
+			Type: "MATCH",
+		},
+		Keyword: nodes[offset],
+	}
+
+	res := matchHeader(nodes, offset+1)
+	if res.End <= res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	node.Subject = res.Node
+	offset = res.End
+
+	if !isPunctuator(nodes[offset], "{") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	defer allowLiterals()()
+	offset++
+
+	for !isPunctuator(nodes[offset], "}") {
+		res := matchArm(nodes, offset)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node.Arms = append(node.Arms, res.Node.(*MatchArm))
+		offset = res.End
+
+		if isPunctuator(nodes[offset], ",") {
+			offset++
+		}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
+
+// MatchMatchStatement matches a match whose value is not used
+func MatchMatchStatement(nodes []types.Node, offset int) types.Result {
+	res := MatchMatch(nodes, offset)
+	if res.End > res.Start {
+		res.Node.(*Match).IsStatement = true
+	}
+
+	return res
+}
@@ -0,0 +1,224 @@
+package rules
+
+import (
+	"go/ast"
+	"slices"
+	"strings"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// Method is a function of the standard library that can be called on its first
+// argument: name.upper() is strings.ToUpper(name)
+type Method struct {
+	Builtin
+	// Params are the types of the arguments after the value, T is the type of
+	// the items of lists
+	Params []string
+	Result string
+}
+
+// Methods are the methods of the types, the methods of "[]" work on all lists
+var Methods = map[string]map[string]Method{
+	"string": {
+		"upper":      {Builtin: Builtin{Package: "strings", Name: "ToUpper"}, Result: "string"},
+		"lower":      {Builtin: Builtin{Package: "strings", Name: "ToLower"}, Result: "string"},
+		"trim":       {Builtin: Builtin{Package: "strings", Name: "TrimSpace"}, Result: "string"},
+		"trimPrefix": {Builtin: Builtin{Package: "strings", Name: "TrimPrefix"}, Params: []string{"string"}, Result: "string"},
+		"trimSuffix": {Builtin: Builtin{Package: "strings", Name: "TrimSuffix"}, Params: []string{"string"}, Result: "string"},
+		"split":      {Builtin: Builtin{Package: "strings", Name: "Split"}, Params: []string{"string"}, Result: "[]string"},
+		"fields":     {Builtin: Builtin{Package: "strings", Name: "Fields"}, Result: "[]string"},
+		"lines":      {Builtin: Builtin{Package: "std", Name: "Lines"}, Result: "[]string"},
+		"contains":   {Builtin: Builtin{Package: "strings", Name: "Contains"}, Params: []string{"string"}, Result: "bool"},
+		"startsWith": {Builtin: Builtin{Package: "strings", Name: "HasPrefix"}, Params: []string{"string"}, Result: "bool"},
+		"endsWith":   {Builtin: Builtin{Package: "strings", Name: "HasSuffix"}, Params: []string{"string"}, Result: "bool"},
+		"replace":    {Builtin: Builtin{Package: "strings", Name: "ReplaceAll"}, Params: []string{"string", "string"}, Result: "string"},
+		"repeat":     {Builtin: Builtin{Package: "strings", Name: "Repeat"}, Params: []string{"int"}, Result: "string"},
+	},
+	"[]string": {
+		"join": {Builtin: Builtin{Package: "strings", Name: "Join"}, Params: []string{"string"}, Result: "string"},
+	},
+	"[]": {
+		"contains": {Builtin: Builtin{Package: "std", Name: "Contains"}, Params: []string{"T"}, Result: "bool"},
+	},
+}
+
+// methodOf returns the method of the standard library with the given name
+// that works on values of the given type
+func methodOf(valueType string, name string) (Method, bool) {
+	if method, ok := Methods[valueType][name]; ok {
+		return method, true
+	}
+
+	itemType := elemType(valueType)
+	method, ok := Methods["[]"][name]
+	if itemType == "" || !ok {
+		return Method{}, false
+	}
+
+	params := []string{}
+	for _, param := range method.Params {
+		if param == "T" {
+			param = itemType
+		}
+		params = append(params, param)
+	}
+
+	method.Params = params
+	return method, true
+}
+
+// methodByName returns the method of the standard library with the given
+// name for values whose type is unknown, the name must belong to one type
+func methodByName(posh *types.PoshFile, name types.Node) (Method, bool) {
+	owners := []string{}
+	for valueType, methods := range Methods {
+		if _, ok := methods[name.GetImage()]; ok {
+			owners = append(owners, valueType)
+		}
+	}
+
+	switch {
+	case len(owners) == 0:
+		posh.Errorf(name, "cannot call %s on a value of unknown type", name.GetImage())
+	case len(owners) > 1 || owners[0] == "[]":
+		// the methods of lists take the type of the items
+		slices.Sort(owners)
+		posh.Errorf(name, "cannot tell which %s to call on a value of unknown type, it is a method of %s",
+			name.GetImage(), strings.Join(owners, " and "))
+	default:
+		return Methods[owners[0]][name.GetImage()], true
+	}
+
+	return Method{}, false
+}
+
+// hasMethods tells if PoSH knows all the methods of a type, the values of the
+// other types are Go values whose methods are called as they are
+func hasMethods(posh *types.PoshFile, valueType string) bool {
+	_, isRecord := posh.Records[valueType]
+	_, isEnum := posh.Enums[valueType]
+	_, value := mapTypes(valueType)
+
+	return isRecord || isEnum || value != "" || elemType(valueType) != "" ||
+		includes([]string{"string", "int", "float64", "bool", "byte"}, valueType)
+}
+
+// methodReceiver returns the value that a call with the method call syntax is
+// made on, and the name of the method: name.upper() or lines(text).join(","),
+// the value is nil for calls of functions and of the members of packages
+func methodReceiver(posh *types.PoshFile, callable types.Node) (types.Node, types.Node) {
+	switch n := callable.(type) {
+	case *Field:
+		return n.Value, n.Name
+	case *DotNotation:
+		valueType, ok := posh.Environment.Get(n.Accessors[0].GetImage())
+		if !ok || strings.HasPrefix(valueType, "module:") {
+			return nil, nil
+		}
+
+		last := len(n.Accessors) - 1
+		if last == 1 {
+			return n.Accessors[0], n.Accessors[1]
+		}
+
+		receiver := &DotNotation{
+			BaseNode:  n.BaseNode,
+			Accessors: n.Accessors[:last],
+		}
+
+		return receiver, n.Accessors[last]
+	}
+
+	return nil, nil
+}
+
+// methodAnalysis resolves the calls with the method call syntax by the type of
+// the value: a function declared with fn whose first param has that type is
+// called with the value as its first argument, or else a method of the
+// standard library. It returns false when the rest of the call is analyzed
+// like any other call.
+func (n *FunctionCall) methodAnalysis(posh *types.PoshFile) bool {
+	receiver, name := methodReceiver(posh, n.Callable)
+	if receiver == nil {
+		return false
+	}
+
+	receiver.StaticAnalysis(posh)
+	valueType := typeOf(posh, receiver)
+
+	// the fields of records can hold functions: host.check()
+	if _, ok := fieldTypeOf(posh.Records[valueType], name.GetImage()); ok {
+		n.Callable = &Field{
+			BaseNode: types.BaseNode{
+				Type: "FIELD_ACCESS",
+			},
+			Value: receiver,
+			Name:  name,
+		}
+
+		methodArgs(posh, n.Args)
+		return true
+	}
+
+	signature, ok := signatureOf(posh, name)
+	if ok && valueType != "" && len(signature.Params) > 0 && !signature.Params[0].Variadic && signature.Params[0].Type == valueType {
+		n.Callable = name
+		n.Receiver = receiver
+		n.Args = append([]types.Node{receiver}, n.Args...)
+		return false
+	}
+
+	method, ok := methodOf(valueType, name.GetImage())
+	if !ok && valueType == "" && !ast.IsExported(name.GetImage()) {
+		method, ok = methodByName(posh, name)
+		if !ok {
+			methodArgs(posh, n.Args)
+			return true
+		}
+	}
+
+	if !ok {
+		if hasMethods(posh, valueType) {
+			posh.Errorf(name, "%s has no method %s", valueType, name.GetImage())
+		} else if dot, ok := n.Callable.(*DotNotation); ok {
+			// a Go value, its methods are exported like its fields
+			dot.IsValue = true
+		}
+
+		methodArgs(posh, n.Args)
+		return true
+	}
+
+	n.Callable = name
+	n.Builtin = &method.Builtin
+	n.Method = &method
+	n.Receiver = receiver
+	posh.StdImports[method.Package] = true
+
+	methodArgs(posh, n.Args)
+	if len(n.Args) < len(method.Params) {
+		posh.Errorf(name, "not enough arguments in call to %s", name.GetImage())
+	} else if len(n.Args) > len(method.Params) {
+		posh.Errorf(n.Args[len(method.Params)], "too many arguments in call to %s", name.GetImage())
+	} else {
+		for i, arg := range n.Args {
+			checkType(posh, []types.Node{arg}, method.Params[i], "argument of type")
+		}
+	}
+
+	n.Args = append([]types.Node{receiver}, n.Args...)
+	return true
+}
+
+// methodArgs analyzes the arguments of a call of a method, only functions
+// declared with fn take named arguments
+func methodArgs(posh *types.PoshFile, args []types.Node) {
+	for _, arg := range args {
+		if named, ok := arg.(*NamedArg); ok {
+			posh.Errorf(named, "named arguments can only be passed to functions declared with fn")
+		} else {
+			arg.StaticAnalysis(posh)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package rules
+
+import "testing"
+
+func TestMethods(t *testing.T) {
+	expectGo(t, []string{`type Host {
+  name string
+  check fn() bool
+}
+
+fn describe(h Host, verbose bool = false) string {
+  return h.name
+}
+
+fn upper(h Host) string {
+  return h.name
+}
+
+fn main() {
+  csv = "  a,b,c  "
+  parts = csv.trim().split(",")
+  io.Println(parts.join("-").upper(), parts.contains("b"))
+  host = Host{name: "db", check: () => true}
+  io.Println(host.describe(verbose=true), host.upper(), host.name.upper(), host.check())
+  hosts = [host]
+  io.Println(hosts[0].describe())
+  for line in echo("a").lines() {
+    io.Println(line)
+  }
+  nums = [1, 2, 3]
+  io.Println(nums.contains(2))
+}`},
+		`parts := strings.Split(strings.TrimSpace(csv), ",")`,
+		`io.Println(strings.ToUpper(strings.Join(parts, "-")), std.Contains(parts, "b"))`,
+		// functions declared with fn come before the methods of the standard
+		// library, and fields that hold functions are called as they are
+		"io.Println(describe(host, true), upper(host), strings.ToUpper(host.Name), host.Check())",
+		"io.Println(describe(hosts[0], false))",
+		`for _, line := range std.Lines(echo(&exec.RunContext{}, "a").Wait().ToString()) {`,
+		"io.Println(std.Contains(nums, 2))",
+	)
+}
+
+func TestMethodsOfUnknownTypes(t *testing.T) {
+	expectGo(t, []string{`fn main() {
+  re = regexp.MustCompile("a+")
+  csv = re.ReplaceAllString("a,aab", "x")
+  io.Println(io.Format("a,%s", "b").split(","), csv.upper(), re.MatchString("caat"))
+}`},
+		// the result types of the standard library are known, the other
+		// methods are taken by their name, Go methods are called as they are
+		`io.Println(strings.Split(io.Format("a,%s", "b"), ","), strings.ToUpper(csv), re.MatchString("caat"))`,
+	)
+}
+
+func TestMethodsOfModules(t *testing.T) {
+	// the members of modules are not methods
+	expectGo(t, []string{`from "/mod1.posh" import * as mod1
+
+fn main() {
+  io.Println(mod1.Upper("a"))
+}`, `fn Upper(s string) string {
+  return s.upper()
+}`},
+		`io.Println(mod1.Upper("a"))`,
+	)
+}
+
+func TestMethodErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"unknown method", "fn main() {\n  s = \"abc\"\n  s.shout()\n}", "string has no method shout"},
+		{"not enough arguments", "fn main() {\n  x = \"abc\".split()\n}", "not enough arguments in call to split"},
+		{"argument type", "fn main() {\n  x = \"abc\".split(1)\n}", "cannot use int as argument of type string"},
+		{"named arguments", "fn main() {\n  x = \"abc\".upper(x=1)\n}", "named arguments can only be passed to functions declared with fn"},
+		{"method of a record", "type Host {\n  name string\n}\n\nfn main() {\n  h = Host{name: \"a\"}\n  h.nope()\n}", "Host has no method nope"},
+		{"unknown type", "fn main() {\n  re = regexp.MustCompile(\"a\")\n  re.matchString(\"a\")\n}", "cannot call matchString on a value of unknown type"},
+		{"ambiguous method", "fn main() {\n  re = regexp.MustCompile(\"a\")\n  io.Println(re.FindString(\"a\").contains(\"a\"))\n}", "cannot tell which contains to call on a value of unknown type, it is a method of [] and string"},
+		{"method of a list", "fn main() {\n  io.Println([1, 2].join(\",\"))\n}", "[]int has no method join"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{test.code}, test.error)
+		})
+	}
+}
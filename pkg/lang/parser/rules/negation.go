@@ -13,37 +13,17 @@ type Negation struct {
 }
 
 func (n *Negation) ToGoAst() ast.Node {
+	value := valueToGoAst(n.Value)
+	if _, ok := value.(*ast.BinaryExpr); ok {
+		value = &ast.ParenExpr{X: value}
+	}
+
 	return &ast.UnaryExpr{
 		Op: token.NOT,
-		X:  n.Value.ToGoAst().(ast.Expr),
+		X:  value,
 	}
 }
 
-func MatchNegation(nodes []types.Node, offset int) types.Result {
-	start := offset
-
-	// We are looking for one of the following:
-	// NOT BOOLEAN
-
-	// try to match NOT
-	if nodes[offset].GetType() != "KEYWORD" || nodes[offset].GetImage() != "not" {
-		return types.Result{FailedAt: &nodes[offset]}
-	}
-	offset++
-
-	// try to match BOOLEAN
-	var res types.Result
-	if res = MatchBoolean(nodes, offset); res.End <= res.Start {
-		return types.Result{FailedAt: &nodes[offset]}
-	}
-	offset = res.End
-
-	node := Negation{
-		BaseNode: types.BaseNode{
-			Type: "NEGATION",
-		},
-		Value: res.Node,
-	}
-
-	return types.Result{Node: &node, Start: start, End: offset + 1}
+func (n *Negation) StaticAnalysis(posh *types.PoshFile) {
+	n.Value.StaticAnalysis(posh)
 }
@@ -9,44 +9,37 @@ import (
 type Numeric struct {
 	types.BaseNode
 	Value types.Node `json:"value"`
+	// Package is set for the variables imported from other modules: lib.Count
+	Package string `json:"package"`
 }
 
 func (n *Numeric) ToGoAst() ast.Node {
+	if n.Package != "" {
+		return &ast.SelectorExpr{
+			X:   ast.NewIdent(n.Package),
+			Sel: ast.NewIdent(n.Value.GetImage()),
+		}
+	}
+
 	return n.Value.ToGoAst()
 }
 
-func MatchNumeric(nodes []types.Node, offset int) types.Result {
-	start := offset
-
-	// We are looking for one of the following:
-	// - WRAPPED_ARITHMETIC
-	// - DOT_NOTATION
-	// - INTEGER
-	// - FLOAT
-	// - IDENTIFIER
-
-	// try to match WRAPPED_ARITHMETIC
-	if res := MatchWrappedArithmetic(nodes, offset); res.End > res.Start {
-		return res
-	}
-
-	// try to match DOT_NOTATION
-	if res := MatchDotNotation(nodes, offset); res.End > res.Start {
-		return res
+func (n *Numeric) StaticAnalysis(posh *types.PoshFile) {
+	if n.Value.GetType() == "IDENTIFIER" {
+		n.Package = importedPackage(posh, n.Value.GetImage())
 	}
+}
 
-	if nodes[offset].GetType() != "INTEGER" &&
-		nodes[offset].GetType() != "FLOAT" &&
-		nodes[offset].GetType() != "IDENTIFIER" {
-		return types.Result{FailedAt: &nodes[offset]}
-	}
+func (n *Numeric) GetPos() *types.Pos {
+	return n.Value.GetPos()
+}
 
-	node := Numeric{
-		BaseNode: types.BaseNode{
-			Type: "NUMERIC",
-		},
-		Value: nodes[offset],
+// bareIdentifier returns the identifier token of an expression that is just
+// an identifier, or nil
+func bareIdentifier(node types.Node) types.Node {
+	if numeric, ok := node.(*Numeric); ok && numeric.Value.GetType() == "IDENTIFIER" {
+		return numeric.Value
 	}
 
-	return types.Result{Node: &node, Start: start, End: offset + 1}
+	return nil
 }
@@ -9,24 +9,105 @@ import (
 
 type Pipe struct {
 	types.BaseNode
-	Value FunctionCall `json:"value"`
+	Value *FunctionCall `json:"value"`
 }
 
 func (n *Pipe) ToGoAst() ast.Node {
 	return n.Value.ToGoAst()
 }
 
+func (n *Pipe) ToGoStatementAst() ast.Stmt {
+	// a pipe that ends with a command streams its output: exec.Run(pipe)
+	if n.Value.IsCommand {
+		return &ast.ExprStmt{
+			X: &ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "exec"},
+					Sel: &ast.Ident{Name: "Run"},
+				},
+				Args: []ast.Expr{n.ToGoAst().(ast.Expr)},
+			},
+		}
+	}
+
+	return &ast.ExprStmt{
+		X: n.ToGoAst().(ast.Expr),
+	}
+}
+
 func (n *Pipe) StaticAnalysis(posh *types.PoshFile) {
 	posh.StdImports["exec"] = true
 	n.Value.StaticAnalysis(posh)
 }
 
+// IsResult tells if the pipe ends with complete(), which turns its outcome
+// into an exec.Result instead of its output
+func (n *Pipe) IsResult() bool {
+	return n.Value.Builtin != nil && n.Value.Builtin.Name == "Complete"
+}
+
+// resultRecord describes the fields of an exec.Result like a record
+var resultRecord = types.Export{
+	Type:   "*exec.Result",
+	IsType: true,
+	Fields: []types.Param{
+		{Name: "stdout", Type: "string"},
+		{Name: "stderr", Type: "string"},
+		{Name: "code", Type: "int"},
+		{Name: "ok", Type: "bool"},
+		{Name: "duration", Type: "time.Duration"},
+		{Name: "lines", Type: "[]string"},
+	},
+}
+
+// valueToGoAst returns the Go expression of a value, pipes are waited for and
+// converted to their output: pipe.Wait().ToString()
+func valueToGoAst(node types.Node) ast.Expr {
+	expr := node.ToGoAst().(ast.Expr)
+
+	if pipe, ok := node.(*Pipe); !ok || pipe.IsResult() {
+		return expr
+	}
+
+	expr = &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   expr,
+			Sel: &ast.Ident{Name: "Wait"},
+		},
+		Args: []ast.Expr{},
+	}
+
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   expr,
+			Sel: &ast.Ident{Name: "ToString"},
+		},
+		Args: []ast.Expr{},
+	}
+}
+
 type RunContext struct {
 	types.BaseNode
+	Stdin types.Node `json:"stdin"`
 }
 
 func (n *RunContext) ToGoAst() ast.Node {
-	// this is &exec.RunContext{}
+	// this is &exec.RunContext{} or &exec.RunContext{Stdout: exec.Input(value)}
+	elts := []ast.Expr{}
+	if n.Stdin != nil {
+		// the stdin of the first command: Stdout: exec.Input(value)
+		elts = append(elts, &ast.KeyValueExpr{
+			Key: &ast.Ident{Name: "Stdout"},
+			Value: &ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "exec"},
+					Sel: &ast.Ident{Name: "Input"},
+				},
+				Args: []ast.Expr{n.Stdin.ToGoAst().(ast.Expr)},
+			},
+		})
+	}
+
 	return &ast.UnaryExpr{
 		Op: token.AND,
 		X: &ast.CompositeLit{
@@ -34,55 +115,30 @@ func (n *RunContext) ToGoAst() ast.Node {
 				X:   &ast.Ident{Name: "exec"},
 				Sel: &ast.Ident{Name: "RunContext"},
 			},
+			Elts: elts,
 		},
 	}
 }
 
-func MatchPipe(nodes []types.Node, offset int) types.Result {
-	start := offset
-	// We're looking for the following:
-	// SimpleExpression (| FunctionCall)+
-
-	node := Pipe{
-		BaseNode: types.BaseNode{
-			Type: "PIPE",
-		},
+func (n *RunContext) StaticAnalysis(posh *types.PoshFile) {
+	if n.Stdin != nil {
+		n.Stdin.StaticAnalysis(posh)
 	}
+}
 
-	args := []types.Node{&RunContext{}}
-
-	// look for the simple expression and add it to the args
-	if res := MatchSimpleExpression(nodes, offset); res.End > res.Start {
-		args = append(args, res.Node)
-		offset = res.End
-	} else {
-		return types.Result{FailedAt: res.FailedAt}
-	}
+func MatchPipe(nodes []types.Node, offset int) types.Result {
+	// We're looking for the following:
+	// EXPRESSION (| FunctionCall)+
+	// pipes are parsed along with the other operators, see matchPipeStage
 
-	// if there's no pipe this is not a match
-	if offset >= len(nodes) || nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != "|" {
-		return types.Result{FailedAt: &nodes[offset]}
+	res := MatchExpr(nodes, offset)
+	if res.End <= res.Start {
+		return res
 	}
 
-	// recursively look for (| FunctionCall)
-	for {
-		if nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != "|" {
-			break
-		}
-
-		offset++
-
-		if res := MatchFunctionCall(nodes, offset); res.End > res.Start {
-			call := res.Node.(*FunctionCall)
-			// prepend the args to the call args
-			call.Args = append(args, call.Args...)
-			args = []types.Node{call}
-			node.Value = *call
-			offset = res.End
-		} else {
-			break
-		}
+	if _, ok := res.Node.(*Pipe); !ok {
+		return types.Result{FailedAt: &nodes[res.End]}
 	}
 
-	return types.Result{Node: &node, Start: start, End: offset}
+	return res
 }
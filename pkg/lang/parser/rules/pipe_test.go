@@ -0,0 +1,23 @@
+package rules
+
+import "testing"
+
+func TestPipes(t *testing.T) {
+	expectGo(t, []string{`fn main(name string) {
+  name | tr("a-z", "A-Z")
+  ls("/") | wc(-l)
+  out = sh("printf 'b\na\n'") | sort()
+  echo("x y") | bash("tr ' ' '\n'") | wc(-l)
+  res = sh("exit 3") | complete()
+  io.Println(out, ls("/"), res.code)
+}`},
+		// values are passed as arguments, commands stream their output
+		`exec.Run(tr(&exec.RunContext{}, name, "a-z", "A-Z"))`,
+		`exec.Run(wc(ls(&exec.RunContext{}, "/"), "-l"))`,
+		`out := sort(exec.Sh(&exec.RunContext{}, "printf 'b\na\n'")).Wait().ToString()`,
+		`exec.Run(wc(exec.Bash(echo(&exec.RunContext{}, "x y"), "tr ' ' '\n'"), "-l"))`,
+		`res := exec.Complete(exec.Sh(&exec.RunContext{}, "exit 3"))`,
+		// outside of pipes commands evaluate to their output
+		`io.Println(out, ls(&exec.RunContext{}, "/").Wait().ToString(), res.Code)`,
+	)
+}
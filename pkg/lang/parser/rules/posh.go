@@ -1,6 +1,7 @@
 package rules
 
 import (
+	"errors"
 	"go/ast"
 	"go/token"
 
@@ -14,10 +15,13 @@ type Posh struct {
 }
 
 var StdImports = map[string]string{
-	"exec": "github.com/pouya-eghbali/posh/pkg/exec",
-	"io":   "github.com/pouya-eghbali/posh/pkg/io",
-	"std":  "github.com/pouya-eghbali/posh/pkg/std",
-	"flag": "flag",
+	"exec":    "github.com/pouya-eghbali/posh/pkg/exec",
+	"io":      "github.com/pouya-eghbali/posh/pkg/io",
+	"std":     "github.com/pouya-eghbali/posh/pkg/std",
+	"json":    "github.com/pouya-eghbali/posh/pkg/json",
+	"regexp":  "regexp",
+	"strings": "strings",
+	"flag":    "flag",
 }
 
 func (n *Posh) CompileToGo(posh *types.PoshFile) error {
@@ -27,6 +31,10 @@ func (n *Posh) CompileToGo(posh *types.PoshFile) error {
 	// perform self-analysis
 	n.StaticAnalysis(posh)
 
+	if len(posh.Errors) > 0 {
+		return errors.Join(posh.Errors...)
+	}
+
 	// find all imports first
 	for _, node := range n.Content {
 		if node.GetType() == "IMPORT" {
@@ -53,6 +61,25 @@ func (n *Posh) CompileToGo(posh *types.PoshFile) error {
 		})
 	}
 
+	// add the imported records and enums
+	if len(posh.TopLevelTypes) > 0 {
+		decls = append(decls, &ast.GenDecl{
+			Tok:   token.TYPE,
+			Specs: posh.TopLevelTypes,
+		})
+	}
+
+	// add the record and enum declarations
+	for _, node := range n.Content {
+		if node.GetType() == "RECORD" {
+			decls = append(decls, node.ToGoAst().(ast.Decl))
+		}
+
+		if node.GetType() == "ENUM" {
+			decls = append(decls, node.ToGoAst().(ast.Decl), node.(*Enum).constDecl())
+		}
+	}
+
 	// add the value specs to the decls
 	if len(posh.TopLevelAssignments) > 0 {
 		decls = append(decls, &ast.GenDecl{
@@ -77,17 +104,40 @@ func (n *Posh) CompileToGo(posh *types.PoshFile) error {
 }
 
 func (n *Posh) StaticAnalysis(posh *types.PoshFile) {
-	// find all functions and add them to the environment
+	// the results of pipes that end with complete() have fields like records
+	posh.Records[resultRecord.Type] = resultRecord
+
+	// find all functions, records and enums and add them to the environment
 	for _, node := range n.Content {
+		if node.GetType() == "RECORD" {
+			record := node.(*Record)
+			posh.Records[record.Identifier.GetImage()] = record.export()
+		}
+
+		if node.GetType() == "ENUM" {
+			enum := node.(*Enum)
+			posh.Enums[enum.Identifier.GetImage()] = enum.export()
+		}
+
 		if node.GetType() == "FUNCTION" {
-			// TODO: Environment should be a map of string to types.Export
 			// TODO: Rename types.Export to something more meaningful
-			posh.Environment.Set(node.(*Function).Identifier.GetImage(), "unknown")
+			function := node.(*Function)
+			posh.Functions[function.Identifier.GetImage()] = function.export()
+			posh.Environment.Set(function.Identifier.GetImage(), funcType(function.export()))
+		}
+	}
+
+	// the functions come last, they can use everything else
+	for _, node := range n.Content {
+		if node.GetType() != "FUNCTION" {
+			node.StaticAnalysis(posh)
 		}
 	}
 
 	for _, node := range n.Content {
-		node.StaticAnalysis(posh)
+		if node.GetType() == "FUNCTION" {
+			node.StaticAnalysis(posh)
+		}
 	}
 }
 
@@ -102,36 +152,31 @@ func MatchPosh(nodes []types.Node, offset int) types.Result {
 	}
 
 	for {
-		if offset >= len(nodes) {
+		if nodes[offset].GetType() == "EOF" {
 			break
 		}
 
-		var funRes types.Result
-		var impRes types.Result
-
-		// Match function
-		if funRes = MatchFunction(nodes, offset); funRes.End > funRes.Start {
-			node.Content = append(node.Content, funRes.Node)
-			offset = funRes.End
-			continue
+		// Match function, import, record, enum or declaration, and report the
+		// one that failed the furthest if none of them matches
+		var failedAt *types.Node
+		matched := false
+
+		for _, match := range []func([]types.Node, int) types.Result{MatchFunction, MatchImport, MatchRecord, MatchEnum, MatchDeclaration} {
+			res := match(nodes, offset)
+			if res.End > res.Start {
+				node.Content = append(node.Content, res.Node)
+				offset = res.End
+				matched = true
+				break
+			}
+
+			if failedAt == nil || (*res.FailedAt).GetPos().Offset > (*failedAt).GetPos().Offset {
+				failedAt = res.FailedAt
+			}
 		}
 
-		// Match import
-		if impRes = MatchImport(nodes, offset); impRes.End > impRes.Start {
-			node.Content = append(node.Content, impRes.Node)
-			offset = impRes.End
-			continue
-		}
-
-		funPos := (*funRes.FailedAt).GetPos()
-		impPos := (*impRes.FailedAt).GetPos()
-
-		// Match error
-		// return whichever has a bigger offset
-		if funPos.Line > impPos.Line || (funPos.Line == impPos.Line && funPos.Column > impPos.Column) {
-			return types.Result{FailedAt: funRes.FailedAt}
-		} else {
-			return types.Result{FailedAt: impRes.FailedAt}
+		if !matched {
+			return types.Result{FailedAt: failedAt}
 		}
 	}
 
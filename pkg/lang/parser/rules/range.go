@@ -48,7 +48,8 @@ func MatchRange(nodes []types.Node, offset int) types.Result {
 	start := offset
 
 	// We are looking for the following:
-	// NUMERIC,(,NUMERIC)?..NUMERIC?
+	// EXPRESSION (, EXPRESSION)? .. EXPRESSION?
+	// the expressions can't have comparisons or logical operators
 
 	node := Range{
 		BaseNode: types.BaseNode{
@@ -57,7 +58,7 @@ func MatchRange(nodes []types.Node, offset int) types.Result {
 	}
 
 	// Look for the start of the range
-	if res := MatchNumeric(nodes, offset); res.End > res.Start {
+	if res := matchBinary(nodes, offset, precComparison); res.End > res.Start {
 		node.Start = res.Node
 		offset = res.End
 	} else {
@@ -67,7 +68,7 @@ func MatchRange(nodes []types.Node, offset int) types.Result {
 	// Look for the step of the range
 	if nodes[offset].GetType() == "PUNCTUATOR" && nodes[offset].GetImage() == "," {
 		offset++
-		if res := MatchNumeric(nodes, offset); res.End > res.Start {
+		if res := matchBinary(nodes, offset, precComparison); res.End > res.Start {
 			node.Step = res.Node
 			offset = res.End
 		} else {
@@ -75,16 +76,23 @@ func MatchRange(nodes []types.Node, offset int) types.Result {
 		}
 	}
 
-	// Look for ..
-	for i := 0; i < 2; i++ {
-		if nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != "." {
-			return types.Result{FailedAt: &nodes[offset]}
+	// Ranges count up by one unless a step is given
+	if node.Step == nil {
+		node.Step = &types.TokenNode{
+			BaseNode: types.BaseNode{Type: "INTEGER"},
+			Image:    "1",
+			Pos:      nodes[offset].GetPos(),
 		}
-		offset++
 	}
 
+	// Look for ..
+	if nodes[offset].GetType() != "PUNCTUATOR" || nodes[offset].GetImage() != ".." {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+	offset++
+
 	// Look for the end of the range
-	if res := MatchNumeric(nodes, offset); res.End > res.Start {
+	if res := matchBinary(nodes, offset, precComparison); res.End > res.Start {
 		node.End = &res.Node
 		offset = res.End
 	}
@@ -0,0 +1,348 @@
+package rules
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// Record is a type with named fields: type Host { name string, port int }
+type Record struct {
+	types.BaseNode
+	Keyword    types.Node `json:"keyword"`
+	Identifier types.Node `json:"identifier"`
+	Fields     []Param    `json:"fields"`
+}
+
+func (n *Record) GetPos() *types.Pos {
+	return n.Keyword.GetPos()
+}
+
+// export returns the fields of the record
+func (n *Record) export() types.Export {
+	export := types.Export{
+		Type:   n.Identifier.GetImage(),
+		IsType: true,
+		Fields: []types.Param{},
+	}
+
+	for _, field := range n.Fields {
+		export.Fields = append(export.Fields, types.Param{
+			Name: field.Identifier.GetImage(),
+			Type: field.ParamType.GetImage(),
+		})
+	}
+
+	return export
+}
+
+func (n *Record) StaticAnalysis(posh *types.PoshFile) {
+	// the fields are exported in Go, so name and Name are the same field
+	seen := map[string]string{}
+	for _, field := range n.Fields {
+		name := field.Identifier.GetImage()
+		if other, ok := seen[exportedName(name)]; ok && other == name {
+			posh.Errorf(field.Identifier, "duplicate field %s in type %s", name, n.Identifier.GetImage())
+		} else if ok {
+			posh.Errorf(field.Identifier, "fields %s and %s of type %s are both %s in Go", other, name, n.Identifier.GetImage(), exportedName(name))
+		}
+		seen[exportedName(name)] = name
+	}
+
+	// capitalized types are exported like functions
+	firstLetter := n.Identifier.GetImage()[0]
+	if firstLetter >= 'A' && firstLetter <= 'Z' {
+		posh.Exports[n.Identifier.GetImage()] = n.export()
+	}
+}
+
+func (n *Record) ToGoAst() ast.Node {
+	// fields are exported in Go so they can be encoded to JSON, the tags keep
+	// their PoSH names: Port int `json:"port"`
+	fields := []*ast.Field{}
+	for _, field := range n.Fields {
+		name := field.Identifier.GetImage()
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{{Name: exportedName(name)}},
+			Type:  field.ParamType.ToGoAst().(ast.Expr),
+			Tag: &ast.BasicLit{
+				Kind:  token.STRING,
+				Value: fmt.Sprintf("`json:\"%s\"`", name),
+			},
+		})
+	}
+
+	return &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{
+			&ast.TypeSpec{
+				Name: &ast.Ident{Name: n.Identifier.GetImage()},
+				Type: &ast.StructType{Fields: &ast.FieldList{List: fields}},
+			},
+		},
+	}
+}
+
+func MatchRecord(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// type IDENTIFIER { (IDENTIFIER TYPE (, IDENTIFIER TYPE)* ,?)? }
+
+	// type is not a keyword so commands can still take it as a flag:
+	// find(-type, "f")
+	if nodes[offset].GetType() != "IDENTIFIER" || nodes[offset].GetImage() != "type" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	if nodes[offset].GetType() != "IDENTIFIER" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := Record{
+		BaseNode: types.BaseNode{
+			Type: "RECORD",
+		},
+		Keyword:    nodes[start],
+		Identifier: nodes[offset],
+	}
+
+	offset++
+
+	if !isPunctuator(nodes[offset], "{") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	offset++
+
+	for !isPunctuator(nodes[offset], "}") {
+		if nodes[offset].GetType() != "IDENTIFIER" {
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+
+		field := Param{
+			BaseNode: types.BaseNode{
+				Type: "FIELD",
+			},
+			Identifier: nodes[offset],
+		}
+
+		res := MatchType(nodes, offset+1)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		field.ParamType = res.Node
+		node.Fields = append(node.Fields, field)
+		offset = res.End
+
+		if isPunctuator(nodes[offset], ",") {
+			offset++
+		}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
+
+// RecordLiteral is a value of a record type: Host{name: "db", port: 5432}, the
+// fields that are left out have their zero values
+type RecordLiteral struct {
+	types.BaseNode
+	Identifier types.Node   `json:"identifier"`
+	Names      []types.Node `json:"names"`
+	Values     []types.Node `json:"values"`
+}
+
+func (n *RecordLiteral) GetPos() *types.Pos {
+	return n.Identifier.GetPos()
+}
+
+func (n *RecordLiteral) StaticAnalysis(posh *types.PoshFile) {
+	name := n.Identifier.GetImage()
+	record, ok := posh.Records[name]
+
+	// arrow functions get their types from the fields they are set to
+	for i, field := range n.Names {
+		if fieldType, ok := fieldTypeOf(record, field.GetImage()); ok {
+			expectType(n.Values[i], fieldType)
+		}
+	}
+
+	for _, value := range n.Values {
+		value.StaticAnalysis(posh)
+	}
+
+	if !ok {
+		posh.Errorf(n.Identifier, "unknown type %s", name)
+		return
+	}
+
+	seen := map[string]bool{}
+	for i, field := range n.Names {
+		fieldType, ok := fieldTypeOf(record, field.GetImage())
+		if !ok {
+			posh.Errorf(field, "type %s has no field %s", name, field.GetImage())
+			continue
+		}
+
+		if seen[field.GetImage()] {
+			posh.Errorf(field, "field %s is set more than once", field.GetImage())
+		}
+		seen[field.GetImage()] = true
+
+		checkType(posh, n.Values[i:i+1], fieldType, "field of type")
+	}
+}
+
+func (n *RecordLiteral) ToGoAst() ast.Node {
+	elts := []ast.Expr{}
+	for i, field := range n.Names {
+		elts = append(elts, &ast.KeyValueExpr{
+			Key:   &ast.Ident{Name: exportedName(field.GetImage())},
+			Value: valueToGoAst(n.Values[i]),
+		})
+	}
+
+	return &ast.CompositeLit{
+		Type: &ast.Ident{Name: n.Identifier.GetImage()},
+		Elts: elts,
+	}
+}
+
+// fieldTypeOf returns the type of a field of a record
+func fieldTypeOf(record types.Export, name string) (string, bool) {
+	for _, field := range record.Fields {
+		if field.Name == name {
+			return field.Type, true
+		}
+	}
+
+	return "", false
+}
+
+// isLoopKeyword tells if a node starts a loop, labels are followed by loops
+// while the fields of record literals are followed by values
+func isLoopKeyword(node types.Node) bool {
+	if node.GetType() != "KEYWORD" {
+		return false
+	}
+
+	return node.GetImage() == "for" || node.GetImage() == "while" || node.GetImage() == "loop"
+}
+
+// exprLev is below zero while the header of an if, elif, while, for or match
+// is matched, where a brace after an identifier starts the body and not a
+// record literal, like in Go. Inside of parentheses, brackets and braces
+// literals are allowed again: if h == (Host{}) {}
+var exprLev = 0
+
+// matchHeader matches the condition of an if, elif or while, the iterable of
+// a for or the subject of a match, which are followed by a body
+func matchHeader(nodes []types.Node, offset int) types.Result {
+	defer func(lev int) { exprLev = lev }(exprLev)
+	exprLev = -1
+
+	return MatchExpr(nodes, offset)
+}
+
+// allowLiterals allows record literals in headers until the returned function
+// is called, see exprLev
+func allowLiterals() func() {
+	lev := exprLev
+	exprLev = 0
+
+	return func() { exprLev = lev }
+}
+
+func MatchRecordLiteral(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// IDENTIFIER { (IDENTIFIER : EXPRESSION (, IDENTIFIER : EXPRESSION)* ,?)? }
+
+	// An identifier followed by a block is not a literal in headers, as in
+	// if ok { ... }, and elsewhere only when the block is empty or starts with
+	// a field
+	if exprLev < 0 || nodes[offset].GetType() != "IDENTIFIER" || !isPunctuator(nodes[offset+1], "{") {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	isField := nodes[offset+2].GetType() == "IDENTIFIER" && isPunctuator(nodes[offset+3], ":") && !isLoopKeyword(nodes[offset+4])
+	if !isField && !isPunctuator(nodes[offset+2], "}") {
+		return types.Result{FailedAt: &nodes[offset+2]}
+	}
+
+	node := RecordLiteral{
+		BaseNode: types.BaseNode{
+			Type: "RECORD_LITERAL",
+		},
+		Identifier: nodes[offset],
+	}
+
+	offset += 2
+
+	for !isPunctuator(nodes[offset], "}") {
+		if nodes[offset].GetType() != "IDENTIFIER" {
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+
+		if !isPunctuator(nodes[offset+1], ":") {
+			return types.Result{FailedAt: &nodes[offset+1]}
+		}
+
+		res := MatchExpr(nodes, offset+2)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node.Names = append(node.Names, nodes[offset])
+		node.Values = append(node.Values, res.Node)
+		offset = res.End
+
+		if isPunctuator(nodes[offset], ",") {
+			offset++
+		} else if !isPunctuator(nodes[offset], "}") {
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
+
+// Field is a field of a value that is not a variable: hosts[0].name
+type Field struct {
+	types.BaseNode
+	Value types.Node `json:"value"`
+	Name  types.Node `json:"name"`
+}
+
+func (n *Field) GetPos() *types.Pos {
+	return n.Value.GetPos()
+}
+
+func (n *Field) StaticAnalysis(posh *types.PoshFile) {
+	n.Value.StaticAnalysis(posh)
+
+	valueType := typeOf(posh, n.Value)
+	if valueType == "" {
+		return
+	}
+
+	record, ok := posh.Records[valueType]
+	if !ok {
+		posh.Errorf(n.Name, "%s has no fields", valueType)
+	} else if _, ok := fieldTypeOf(record, n.Name.GetImage()); !ok {
+		posh.Errorf(n.Name, "type %s has no field %s", valueType, n.Name.GetImage())
+	}
+}
+
+func (n *Field) ToGoAst() ast.Node {
+	return &ast.SelectorExpr{
+		X:   valueToGoAst(n.Value),
+		Sel: &ast.Ident{Name: exportedName(n.Name.GetImage())},
+	}
+}
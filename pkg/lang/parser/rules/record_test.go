@@ -0,0 +1,176 @@
+package rules
+
+import "testing"
+
+const hostType = `type Host {
+  name string
+  port int
+}
+`
+
+func TestRecordLiterals(t *testing.T) {
+	expectGo(t, []string{hostType + `
+fn main() {
+  h = Host{name: "db", port: 5432}
+  e = Host{}
+  io.Println(h.name, e.port)
+}`},
+		"type Host struct {",
+		"Name string `json:\"name\"`",
+		"Port int `json:\"port\"`",
+		`h := Host{Name: "db", Port: 5432}`,
+		"e := Host{}",
+		"io.Println(h.Name, e.Port)",
+	)
+}
+
+func TestEmptyBodies(t *testing.T) {
+	// an identifier followed by {} is a value and an empty body, not a record
+	// literal, in the headers of if, elif, while and for
+	expectGo(t, []string{`fn main(v bool, w bool, xs []string) {
+  if v {}
+  if v {} elif w {} else {}
+  while v {}
+  for x in xs {}
+  if v == w {}
+  io.Println(v)
+}`},
+		"if v {",
+		"} else if w {",
+		"for v {",
+		"for _, x := range xs {",
+		"if v == w {",
+		"io.Println(v)",
+	)
+}
+
+func TestRecordLiteralsInHeaders(t *testing.T) {
+	// like in Go, the brace after a name in a header starts the body, so the
+	// literals there are in parentheses, brackets or the arguments of calls
+	expectGo(t, []string{hostType + `
+fn empty(h Host) bool {
+  return h == Host{}
+}
+
+fn check(h Host) void {
+  if h == (Host{}) {
+    io.Println("empty")
+  }
+  while h != (Host{name: "db"}) {
+    h = Host{name: "db"}
+  }
+  for x in [Host{}] {
+    io.Println(x.name)
+  }
+  if empty(Host{port: 1}) {}
+  match h {
+    _ => io.Println(Host{})
+  }
+}`},
+		"return h == Host{}",
+		"if h == (Host{}) {",
+		`for h != (Host{Name: "db"}) {`,
+		"for _, x := range []Host{Host{}} {",
+		"if empty(Host{Port: 1}) {",
+		"io.Println(Host{})",
+	)
+}
+
+func TestRecordLiteralsInHeaderErrors(t *testing.T) {
+	for _, header := range []string{"if h == Host{} {", "while h != Host{name: \"db\"} {", "for x in Host{} {"} {
+		t.Run(header, func(t *testing.T) {
+			expectError(t, []string{hostType + "fn check(h Host) void {\n  " + header + "\n  }\n}"}, "failed to parse")
+		})
+	}
+}
+
+func TestFieldAssignment(t *testing.T) {
+	expectGo(t, []string{hostType + `
+type Cluster {
+  primary Host
+  hosts []Host
+  check fn(Host) bool
+}
+
+fn main() {
+  h = Host{name: "db"}
+  h.port = 8080
+  h.port += 1
+  h.port++
+  h.name += "-1"
+  c = Cluster{primary: h, hosts: [h]}
+  c.primary.name = "main"
+  c.hosts[0].port = 5432
+  c.check = x => x.port > 0
+  if c.check(h) {
+    h.port = 80
+  }
+  io.Println(h, c)
+}`},
+		`h := Host{Name: "db"}`,
+		"h.Port = 8080",
+		"h.Port += 1",
+		"h.Port++",
+		`h.Name += "-1"`,
+		`c.Primary.Name = "main"`,
+		"c.Hosts[0].Port = 5432",
+		"c.Check = func(x Host) bool {",
+		"if c.Check(h) {",
+		"h.Port = 80",
+	)
+}
+
+func TestFieldAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"field type", "h.port = \"x\"", "cannot use string as field of type int"},
+		{"unknown field", "h.user = \"x\"", "type Host has no field user"},
+		{"operator", "h.name -= \"x\"", "operator -= is not defined on string"},
+		{"undeclared value", "g.port = 1", "cannot assign to g, it is not a variable"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{hostType + "fn main() {\n  h = Host{}\n  " + test.code + "\n}"}, test.error)
+		})
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"duplicate field", "type Host {\n  name string\n  name int\n}", "duplicate field name in type Host"},
+		{"fields with the same Go name", "type Host {\n  name string\n  Name int\n}", "fields name and Name of type Host are both Name in Go"},
+		{"unknown field", hostType + "fn main() {\n  h = Host{user: \"x\"}\n}", "type Host has no field user"},
+		{"field set twice", hostType + "fn main() {\n  h = Host{port: 1, port: 2}\n}", "field port is set more than once"},
+		{"field type", hostType + "fn main() {\n  h = Host{port: \"x\"}\n}", "field of type int"},
+		{"unknown type", "fn main() {\n  h = Server{name: \"x\"}\n}", "unknown type Server"},
+		{"unknown field access", hostType + "fn main(h Host) {\n  io.Println(h.user)\n}", "type Host has no field user"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{test.code}, test.error)
+		})
+	}
+}
+
+func TestImportedRecords(t *testing.T) {
+	expectGo(t, []string{`from "/mod1.posh" import Host
+
+fn main() {
+  h = Host{name: "db"}
+  io.Println(h.name)
+}`, `type Host {
+  name string
+}`},
+		`h := Host{Name: "db"}`,
+		"io.Println(h.Name)",
+	)
+}
@@ -0,0 +1,31 @@
+package rules
+
+import "testing"
+
+func TestCommandResults(t *testing.T) {
+	expectGo(t, []string{`fn main() {
+  res = "-r" | grep("x", ".") | complete()
+  if not res.ok {
+    io.Println(res.code, res.stderr)
+  }
+  for line in res.lines {
+    io.Println(line)
+  }
+  io.Println(res.stdout, res.duration)
+}`},
+		// pipes that end with complete() are not waited for and converted to
+		// their output
+		`res := exec.Complete(grep(&exec.RunContext{}, "-r", "x", "."))`,
+		"if !res.Ok {",
+		"io.Println(res.Code, res.Stderr)",
+		"for _, line := range res.Lines {",
+		"io.Println(res.Stdout, res.Duration)",
+	)
+}
+
+func TestCommandResultErrors(t *testing.T) {
+	expectError(t, []string{`fn main() {
+  res = ls() | complete()
+  io.Println(res.status)
+}`}, "type *exec.Result has no field status")
+}
@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+var retryBackoffs = map[string]string{
+	"constant":    "Constant",
+	"linear":      "Linear",
+	"exponential": "Exponential",
+}
+
+// Retry runs its body again when it fails, with a delay between attempts:
+// retry(times=5, backoff=exponential, delay=1) { ... }
+// delay is in seconds and backoff is one of constant, linear or exponential.
+type Retry struct {
+	types.BaseNode
+	Keyword types.Node    `json:"keyword"`
+	Options []*NamedArg   `json:"options"`
+	Body    *FunctionBody `json:"body"`
+	// Variables are declared in the body and before the retry, see blockBody
+	Variables []types.Param `json:"variables"`
+}
+
+func (n *Retry) GetPos() *types.Pos {
+	return n.Keyword.GetPos()
+}
+
+func (n *Retry) hoist() []types.Param {
+	variables := n.Variables
+	n.Variables = nil
+	return variables
+}
+
+func (n *Retry) option(name string) types.Node {
+	for _, option := range n.Options {
+		if option.Name.GetImage() == name {
+			return option.Value
+		}
+	}
+
+	return nil
+}
+
+func (n *Retry) ToGoStatements() []ast.Stmt {
+	// defaults are 3 attempts with an exponential backoff starting at 1s
+	var times ast.Expr = &ast.BasicLit{Kind: token.INT, Value: "3"}
+	if value := n.option("times"); value != nil {
+		times = value.ToGoAst().(ast.Expr)
+	}
+
+	backoff := "Exponential"
+	if value := n.option("backoff"); value != nil {
+		backoff = retryBackoffs[bareIdentifier(value).GetImage()]
+	}
+
+	var delay ast.Expr = &ast.BasicLit{Kind: token.INT, Value: "1"}
+	if value := n.option("delay"); value != nil {
+		delay = value.ToGoAst().(ast.Expr)
+	}
+
+	// exec.Retry(exec.Context(), exec.RetryOptions{...}, func() { ... })
+	return blockStatements(n.Variables, &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   &ast.Ident{Name: "exec"},
+			Sel: &ast.Ident{Name: "Retry"},
+		},
+		Args: []ast.Expr{
+			&ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "exec"},
+					Sel: &ast.Ident{Name: "Context"},
+				},
+			},
+			&ast.CompositeLit{
+				Type: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "exec"},
+					Sel: &ast.Ident{Name: "RetryOptions"},
+				},
+				Elts: []ast.Expr{
+					&ast.KeyValueExpr{
+						Key:   &ast.Ident{Name: "Times"},
+						Value: times,
+					},
+					&ast.KeyValueExpr{
+						Key: &ast.Ident{Name: "Backoff"},
+						Value: &ast.SelectorExpr{
+							X:   &ast.Ident{Name: "exec"},
+							Sel: &ast.Ident{Name: backoff},
+						},
+					},
+					&ast.KeyValueExpr{
+						Key: &ast.Ident{Name: "Delay"},
+						Value: &ast.CallExpr{
+							Fun: &ast.SelectorExpr{
+								X:   &ast.Ident{Name: "exec"},
+								Sel: &ast.Ident{Name: "Seconds"},
+							},
+							Args: []ast.Expr{delay},
+						},
+					},
+				},
+			},
+			&ast.FuncLit{
+				Type: &ast.FuncType{},
+				Body: n.Body.ToGoAst().(*ast.BlockStmt),
+			},
+		},
+	})
+}
+
+func (n *Retry) StaticAnalysis(posh *types.PoshFile) {
+	seen := map[string]bool{}
+
+	for _, option := range n.Options {
+		name := option.Name.GetImage()
+
+		if !includes([]string{"times", "backoff", "delay"}, name) {
+			posh.Errorf(option, "unknown retry option %s", name)
+		} else if seen[name] {
+			posh.Errorf(option, "duplicate retry option %s", name)
+		}
+
+		seen[name] = true
+
+		// backoff is a bare identifier, not a variable
+		if name == "backoff" {
+			if identifier := bareIdentifier(option.Value); identifier == nil || retryBackoffs[identifier.GetImage()] == "" {
+				posh.Errorf(option, "backoff must be one of constant, linear or exponential")
+			}
+		} else {
+			option.StaticAnalysis(posh)
+		}
+	}
+
+	posh.StdImports["exec"] = true
+
+	n.Variables = blockBody(posh, "retry", n.Body)
+}
+
+func MatchRetry(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// RETRY ( (NAMED_ARG (, NAMED_ARG)*)? ) BODY
+
+	if nodes[offset].GetType() != "KEYWORD" || nodes[offset].GetImage() != "retry" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := Retry{
+		BaseNode: types.BaseNode{
+			Type: "RETRY",
+		},
+		Keyword: nodes[offset],
+	}
+
+	offset++
+
+	res := MatchOptions(nodes, offset)
+	if res.End == res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	node.Options = res.Node.(*Options).Options
+	offset = res.End
+
+	if res := MatchFunctionBody(nodes, offset); res.End > res.Start {
+		node.Body = res.Node.(*FunctionBody)
+		offset = res.End
+	} else {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset}
+}
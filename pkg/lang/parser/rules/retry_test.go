@@ -0,0 +1,45 @@
+package rules
+
+import "testing"
+
+func TestRetry(t *testing.T) {
+	expectGo(t, []string{`fn main() {
+  retry(times=5, backoff=linear, delay=0.5) {
+    out = curl("https://example.com")
+    retry() {
+      status = 200
+    }
+  }
+  io.Println(out, status)
+}`},
+		// the variables of the body are declared before it
+		"var out string",
+		"var status int",
+		"exec.Retry(exec.Context(), exec.RetryOptions{Times: 5, Backoff: exec.Linear, Delay: exec.Seconds(0.5)}, func() {",
+		`out = curl(&exec.RunContext{}, "https://example.com").Wait().ToString()`,
+		"exec.Retry(exec.Context(), exec.RetryOptions{Times: 3, Backoff: exec.Exponential, Delay: exec.Seconds(1)}, func() {",
+		"status = 200",
+		"io.Println(out, status)",
+	)
+}
+
+func TestRetryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"unknown option", "retry(tries=1) {\n  }", "unknown retry option tries"},
+		{"duplicate option", "retry(times=1, times=2) {\n  }", "duplicate retry option times"},
+		{"backoff", "retry(backoff=quadratic) {\n  }", "backoff must be one of constant, linear or exponential"},
+		{"return", "retry() {\n    return\n  }", "return is not allowed inside of a retry block"},
+		{"continue", "for i in 0..3 {\n    retry() {\n      continue\n    }\n  }", "continue can not leave a retry block"},
+		{"unknown type", "retry() {\n    re = regexp.MustCompile(\"a\")\n  }", "the type of re is unknown, declare it before the retry block: var re TYPE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{"fn main() {\n  " + test.code + "\n}"}, test.error)
+		})
+	}
+}
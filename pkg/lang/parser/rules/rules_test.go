@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/utils"
+)
+
+// compile compiles the files of a PoSH program, the first one is the main
+// file, and returns the Go code of the main file or the compile error
+func compile(t *testing.T, files ...string) (string, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for i, code := range files {
+		name := "main.posh"
+		if i > 0 {
+			name = "mod" + string(rune('0'+i)) + ".posh"
+		}
+
+		if err := os.WriteFile(path.Join(dir, name), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := path.Join(dir, "out")
+	posh := types.NewPoshFile("main.posh", dir, output, "main", map[string]types.CompiledFile{})
+	if err := utils.CompilePoshFile(posh, MatchPosh); err != nil {
+		return "", err
+	}
+
+	code, err := os.ReadFile(path.Join(output, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(code), nil
+}
+
+// expectGo compiles a PoSH program and checks that its Go code has the
+// expected lines in the given order, ignoring their indentation and the width
+// of the spaces in them
+func expectGo(t *testing.T, files []string, expected ...string) {
+	t.Helper()
+
+	code, err := compile(t, files...)
+	if err != nil {
+		t.Fatalf("failed to compile: %v", err)
+	}
+
+	lines := strings.Split(code, "\n")
+	next := 0
+
+	for _, line := range expected {
+		found := false
+		for next < len(lines) && !found {
+			found = strings.Join(strings.Fields(lines[next]), " ") == line
+			next++
+		}
+
+		if !found {
+			t.Fatalf("expected the line %q, in this order, in:\n%s", line, code)
+		}
+	}
+}
+
+// expectError compiles a PoSH program and checks that it fails with an error
+// that contains the expected message
+func expectError(t *testing.T, files []string, expected string) {
+	t.Helper()
+
+	_, err := compile(t, files...)
+	if err == nil {
+		t.Fatalf("expected the error %q, the program compiled", expected)
+	}
+
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected the error %q, got: %v", expected, err)
+	}
+}
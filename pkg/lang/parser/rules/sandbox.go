@@ -0,0 +1,146 @@
+package rules
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// sandboxOptions maps the options of a sandbox block to exec.Limits fields
+var sandboxOptions = map[string]string{
+	"cpu":    "CPU",
+	"memory": "Memory",
+	"files":  "Files",
+	"procs":  "Procs",
+	"group":  "NewGroup",
+	"uid":    "Uid",
+}
+
+// Sandbox runs the commands started in its body, and in the functions it
+// calls, with resource limits:
+// sandbox(cpu=10, memory=268435456, files=64, procs=32, group=true, uid=65534) { ... }
+// In nested sandboxes each limit is the lowest of all of them, see exec.Sandbox.
+type Sandbox struct {
+	types.BaseNode
+	Keyword types.Node    `json:"keyword"`
+	Options []*NamedArg   `json:"options"`
+	Body    *FunctionBody `json:"body"`
+	// Variables are declared in the body and before the sandbox, see blockBody
+	Variables []types.Param `json:"variables"`
+}
+
+func (n *Sandbox) GetPos() *types.Pos {
+	return n.Keyword.GetPos()
+}
+
+func (n *Sandbox) hoist() []types.Param {
+	variables := n.Variables
+	n.Variables = nil
+	return variables
+}
+
+func (n *Sandbox) ToGoStatements() []ast.Stmt {
+	elts := []ast.Expr{}
+
+	for _, option := range n.Options {
+		value := option.Value.ToGoAst().(ast.Expr)
+
+		// uid is optional in exec.Limits, so it's a pointer
+		if option.Name.GetImage() == "uid" {
+			value = &ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X:   &ast.Ident{Name: "exec"},
+					Sel: &ast.Ident{Name: "User"},
+				},
+				Args: []ast.Expr{value},
+			}
+		}
+
+		elts = append(elts, &ast.KeyValueExpr{
+			Key:   &ast.Ident{Name: sandboxOptions[option.Name.GetImage()]},
+			Value: value,
+		})
+	}
+
+	// exec.Sandbox(&exec.Limits{CPU: 10}, func() { ... })
+	return blockStatements(n.Variables, &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   &ast.Ident{Name: "exec"},
+			Sel: &ast.Ident{Name: "Sandbox"},
+		},
+		Args: []ast.Expr{
+			&ast.UnaryExpr{
+				Op: token.AND,
+				X: &ast.CompositeLit{
+					Type: &ast.SelectorExpr{
+						X:   &ast.Ident{Name: "exec"},
+						Sel: &ast.Ident{Name: "Limits"},
+					},
+					Elts: elts,
+				},
+			},
+			&ast.FuncLit{
+				Type: &ast.FuncType{},
+				Body: n.Body.ToGoAst().(*ast.BlockStmt),
+			},
+		},
+	})
+}
+
+func (n *Sandbox) StaticAnalysis(posh *types.PoshFile) {
+	seen := map[string]bool{}
+
+	for _, option := range n.Options {
+		name := option.Name.GetImage()
+
+		if _, ok := sandboxOptions[name]; !ok {
+			posh.Errorf(option, "unknown sandbox option %s", name)
+		} else if seen[name] {
+			posh.Errorf(option, "duplicate sandbox option %s", name)
+		}
+
+		seen[name] = true
+		option.StaticAnalysis(posh)
+	}
+
+	posh.StdImports["exec"] = true
+	n.Variables = blockBody(posh, "sandbox", n.Body)
+}
+
+func MatchSandbox(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// SANDBOX ( (NAMED_ARG (, NAMED_ARG)*)? ) BODY
+
+	if nodes[offset].GetType() != "KEYWORD" || nodes[offset].GetImage() != "sandbox" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := Sandbox{
+		BaseNode: types.BaseNode{
+			Type: "SANDBOX",
+		},
+		Keyword: nodes[offset],
+	}
+
+	offset++
+
+	res := MatchOptions(nodes, offset)
+	if res.End == res.Start {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	node.Options = res.Node.(*Options).Options
+	offset = res.End
+
+	if res := MatchFunctionBody(nodes, offset); res.End > res.Start {
+		node.Body = res.Node.(*FunctionBody)
+		offset = res.End
+	} else {
+		return types.Result{FailedAt: res.FailedAt}
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset}
+}
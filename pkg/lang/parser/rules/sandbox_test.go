@@ -0,0 +1,62 @@
+package rules
+
+import "testing"
+
+func TestSandbox(t *testing.T) {
+	expectGo(t, []string{`fn build() string {
+  return make()
+}
+
+fn main() {
+  sandbox(cpu=10, files=64, group=true, uid=65534) {
+    out = build()
+    sandbox(memory=268435456) {
+      host, port = "db", 5432
+      var retries int
+    }
+    for i in 0..3 {
+      if i == 1 {
+        break
+      }
+    }
+  }
+  io.Println(out, host, port, retries)
+}`},
+		// the limits apply to the functions called in the sandbox too, so
+		// they are set at runtime
+		"return make(&exec.RunContext{}).Wait().ToString()",
+		// the variables of the body are declared before it
+		"var out string",
+		"var host string",
+		"var port int",
+		"var retries int",
+		"exec.Sandbox(&exec.Limits{CPU: 10, Files: 64, NewGroup: true, Uid: exec.User(65534)}, func() {",
+		"out = build()",
+		"exec.Sandbox(&exec.Limits{Memory: 268435456}, func() {",
+		`host, port = "db", 5432`,
+		"retries = *new(int)",
+		"break",
+		"io.Println(out, host, port, retries)",
+	)
+}
+
+func TestSandboxErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"unknown option", "sandbox(disk=1) {\n  }", "unknown sandbox option disk"},
+		{"duplicate option", "sandbox(cpu=1, cpu=2) {\n  }", "duplicate sandbox option cpu"},
+		{"return", "sandbox(cpu=1) {\n    return\n  }", "return is not allowed inside of a sandbox block"},
+		{"break", "for i in 0..3 {\n    sandbox(cpu=1) {\n      break\n    }\n  }", "break can not leave a sandbox block"},
+		{"unknown type", "sandbox(cpu=1) {\n    re = regexp.MustCompile(\"a\")\n  }", "the type of re is unknown, declare it before the sandbox block: var re TYPE"},
+		{"const", "sandbox(cpu=1) {\n    const c = 1\n  }\n  c = 2", "cannot assign to c, it is a const declared at 3:11"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectError(t, []string{"fn main() {\n  " + test.code + "\n}"}, test.error)
+		})
+	}
+}
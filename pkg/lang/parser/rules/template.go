@@ -0,0 +1,113 @@
+package rules
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// Template is a string with interpolated expressions: "Hello ${name}"
+type Template struct {
+	types.BaseNode
+	Parts  []types.Node `json:"parts"`
+	Values []types.Node `json:"values"`
+	Verbs  []string     `json:"verbs"`
+}
+
+// formatVerbs maps the known types of interpolated values to their verbs
+var formatVerbs = map[string]string{
+	"string":  "%s",
+	"int":     "%d",
+	"float64": "%g",
+	"bool":    "%t",
+}
+
+func (n *Template) GetPos() *types.Pos {
+	return n.Parts[0].GetPos()
+}
+
+func (n *Template) StaticAnalysis(posh *types.PoshFile) {
+	posh.StdImports["io"] = true
+
+	n.Verbs = []string{}
+	for _, value := range n.Values {
+		value.StaticAnalysis(posh)
+
+		verb, ok := formatVerbs[typeOf(posh, value)]
+		if !ok {
+			verb = "%v"
+		}
+		n.Verbs = append(n.Verbs, verb)
+	}
+}
+
+func (n *Template) ToGoAst() ast.Node {
+	// return io.Format("Hello %s", name)
+	var format strings.Builder
+	args := []ast.Expr{}
+
+	for i, part := range n.Parts {
+		format.WriteString(strings.ReplaceAll(part.(*types.TokenNode).Value, "%", "%%"))
+
+		if i < len(n.Values) {
+			format.WriteString(n.Verbs[i])
+			args = append(args, valueToGoAst(n.Values[i]))
+		}
+	}
+
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   &ast.Ident{Name: "io"},
+			Sel: &ast.Ident{Name: "Format"},
+		},
+		Args: append([]ast.Expr{
+			&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(format.String())},
+		}, args...),
+	}
+}
+
+func MatchTemplate(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for the following:
+	// TEMPLATE_BEGIN EXPR (TEMPLATE_MIDDLE EXPR)* TEMPLATE_END
+
+	if nodes[offset].GetType() != "TEMPLATE_BEGIN" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	defer allowLiterals()()
+
+	node := Template{
+		BaseNode: types.BaseNode{
+			Type: "TEMPLATE",
+		},
+		Parts: []types.Node{nodes[offset]},
+	}
+
+	offset++
+
+	for {
+		res := MatchExpr(nodes, offset)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node.Values = append(node.Values, res.Node)
+		offset = res.End
+
+		switch nodes[offset].GetType() {
+		case "TEMPLATE_MIDDLE":
+			node.Parts = append(node.Parts, nodes[offset])
+			offset++
+		case "TEMPLATE_END":
+			node.Parts = append(node.Parts, nodes[offset])
+			return types.Result{Node: &node, Start: start, End: offset + 1}
+		default:
+			return types.Result{FailedAt: &nodes[offset]}
+		}
+	}
+}
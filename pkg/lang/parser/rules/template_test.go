@@ -0,0 +1,25 @@
+package rules
+
+import "testing"
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		expr string
+		goIs string
+	}{
+		{`"${name} is ${age}"`, `x := io.Format("%s is %d", name, age)`},
+		{`"${ratio} ${ok} ${xs}"`, `x := io.Format("%g %t %v", ratio, ok, xs)`},
+		{`"${age + 1}%"`, `x := io.Format("%d%%", age+1)`},
+		{`"${"a" | tr("a", "b")}"`, `x := io.Format("%s", tr(&exec.RunContext{}, "a", "a", "b").Wait().ToString())`},
+		{`"\${name}"`, `x := "${name}"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expectGo(t, []string{`fn f(name string, age int, ratio float64, ok bool, xs []int) void {
+  x = ` + test.expr + `
+  io.Println(x)
+}`}, test.goIs)
+		})
+	}
+}
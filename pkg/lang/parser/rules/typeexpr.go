@@ -0,0 +1,306 @@
+package rules
+
+import (
+	"go/ast"
+	"go/parser"
+	gotypes "go/types"
+	"strings"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// TypeNode is a type expression: string, []string, map[string]int
+type TypeNode struct {
+	types.BaseNode
+	Pos *types.Pos `json:"pos"`
+	// Name is the Go spelling of the type, it is also used as its name in
+	// the environment
+	Name string `json:"name"`
+}
+
+func (n *TypeNode) GetPos() *types.Pos {
+	return n.Pos
+}
+
+func (n *TypeNode) GetImage() string {
+	return n.Name
+}
+
+func (n *TypeNode) ToGoAst() ast.Node {
+	return typeExpr(n.Name)
+}
+
+// typeExpr returns the Go ast of a type, the names of the types are made by
+// MatchType so they are always valid
+func typeExpr(name string) ast.Expr {
+	expr, _ := parser.ParseExpr(name)
+	return expr
+}
+
+// elemType returns the item type of a list type, or an empty string if the
+// type is not a list
+func elemType(valueType string) string {
+	if !strings.HasPrefix(valueType, "[]") {
+		return ""
+	}
+
+	return strings.TrimPrefix(valueType, "[]")
+}
+
+// mapTypes returns the key and value types of a map type, or empty strings if
+// the type is not a map
+func mapTypes(valueType string) (string, string) {
+	expr, ok := typeExpr(valueType).(*ast.MapType)
+	if !ok {
+		return "", ""
+	}
+
+	return gotypes.ExprString(expr.Key), gotypes.ExprString(expr.Value)
+}
+
+// seqTypes returns the types of the pairs of an iter.Seq2, or empty strings if
+// the type is not an iter.Seq2
+func seqTypes(valueType string) (string, string) {
+	expr, ok := typeExpr(valueType).(*ast.IndexListExpr)
+	if !ok || gotypes.ExprString(expr.X) != "iter.Seq2" {
+		return "", ""
+	}
+
+	return gotypes.ExprString(expr.Indices[0]), gotypes.ExprString(expr.Indices[1])
+}
+
+// tupleTypes returns the types of a tuple type: (string, int), or nil if the
+// type is not a tuple
+func tupleTypes(valueType string) []string {
+	if !strings.HasPrefix(valueType, "(") || !strings.HasSuffix(valueType, ")") {
+		return nil
+	}
+
+	// the types can have commas of their own: (func(int, int) int, error)
+	tuple := []string{}
+	depth, start := 0, 1
+	for i := 1; i < len(valueType)-1; i++ {
+		switch valueType[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				tuple = append(tuple, strings.TrimSpace(valueType[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	return append(tuple, strings.TrimSpace(valueType[start:len(valueType)-1]))
+}
+
+// funcTypes returns the types of the params and the result of a function
+// type, variadic params start with ...
+func funcTypes(valueType string) ([]string, string, bool) {
+	expr, ok := typeExpr(valueType).(*ast.FuncType)
+	if !ok {
+		return nil, "", false
+	}
+
+	params := []string{}
+	for _, field := range expr.Params.List {
+		params = append(params, gotypes.ExprString(field.Type))
+	}
+
+	results := []string{}
+	if expr.Results != nil {
+		for _, field := range expr.Results.List {
+			results = append(results, gotypes.ExprString(field.Type))
+		}
+	}
+
+	switch len(results) {
+	case 0:
+		return params, "void", true
+	case 1:
+		return params, results[0], true
+	}
+
+	return params, "(" + strings.Join(results, ", ") + ")", true
+}
+
+// resultFields returns the results of a Go function type
+func resultFields(resultType string) *ast.FieldList {
+	if resultType == "void" {
+		return nil
+	}
+
+	results := tupleTypes(resultType)
+	if results == nil {
+		results = []string{resultType}
+	}
+
+	fields := &ast.FieldList{}
+	for _, result := range results {
+		fields.List = append(fields.List, &ast.Field{Type: typeExpr(result)})
+	}
+
+	return fields
+}
+
+// MatchResultType matches the result type of a function, which can be a
+// tuple: (string, int)
+func MatchResultType(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for one of the following:
+	// - ( TYPE (, TYPE)+ )
+	// - TYPE
+
+	if !isPunctuator(nodes[offset], "(") {
+		return MatchType(nodes, offset)
+	}
+
+	offset++
+
+	names := []string{}
+	for {
+		res := MatchType(nodes, offset)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		names = append(names, res.Node.GetImage())
+		offset = res.End
+
+		if !isPunctuator(nodes[offset], ",") {
+			break
+		}
+
+		offset++
+	}
+
+	if !isPunctuator(nodes[offset], ")") || len(names) < 2 {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := TypeNode{
+		BaseNode: types.BaseNode{
+			Type: "TYPE",
+		},
+		Pos:  nodes[start].GetPos(),
+		Name: "(" + strings.Join(names, ", ") + ")",
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
+
+func MatchType(nodes []types.Node, offset int) types.Result {
+	start := offset
+
+	// We are looking for one of the following:
+	// - [ ] TYPE
+	// - map [ TYPE ] TYPE
+	// - fn ( (...? TYPE (, ...? TYPE)*)? ) RESULT_TYPE?
+	// - IDENTIFIER
+
+	if isPunctuator(nodes[offset], "[") && isPunctuator(nodes[offset+1], "]") {
+		res := MatchType(nodes, offset+2)
+		if res.End <= res.Start {
+			return types.Result{FailedAt: res.FailedAt}
+		}
+
+		node := TypeNode{
+			BaseNode: types.BaseNode{
+				Type: "TYPE",
+			},
+			Pos:  nodes[offset].GetPos(),
+			Name: "[]" + res.Node.GetImage(),
+		}
+
+		return types.Result{Node: &node, Start: start, End: res.End}
+	}
+
+	if nodes[offset].GetImage() == "map" && isPunctuator(nodes[offset+1], "[") {
+		key := MatchType(nodes, offset+2)
+		if key.End <= key.Start {
+			return types.Result{FailedAt: key.FailedAt}
+		}
+
+		if !isPunctuator(nodes[key.End], "]") {
+			return types.Result{FailedAt: &nodes[key.End]}
+		}
+
+		value := MatchType(nodes, key.End+1)
+		if value.End <= value.Start {
+			return types.Result{FailedAt: value.FailedAt}
+		}
+
+		node := TypeNode{
+			BaseNode: types.BaseNode{
+				Type: "TYPE",
+			},
+			Pos:  nodes[offset].GetPos(),
+			Name: "map[" + key.Node.GetImage() + "]" + value.Node.GetImage(),
+		}
+
+		return types.Result{Node: &node, Start: start, End: value.End}
+	}
+
+	// function types have the same names as in Go: func(string) bool
+	if nodes[offset].GetType() == "KEYWORD" && nodes[offset].GetImage() == "fn" && isPunctuator(nodes[offset+1], "(") {
+		export := types.Export{Type: "void"}
+		offset += 2
+
+		for !isPunctuator(nodes[offset], ")") {
+			param := types.Param{}
+			if isPunctuator(nodes[offset], "...") {
+				param.Variadic = true
+				offset++
+			}
+
+			res := MatchType(nodes, offset)
+			if res.End <= res.Start {
+				return types.Result{FailedAt: res.FailedAt}
+			}
+
+			param.Type = res.Node.GetImage()
+			export.Params = append(export.Params, param)
+			offset = res.End
+
+			if isPunctuator(nodes[offset], ",") {
+				offset++
+			} else if !isPunctuator(nodes[offset], ")") {
+				return types.Result{FailedAt: &nodes[offset]}
+			}
+		}
+
+		offset++
+
+		if res := MatchResultType(nodes, offset); res.End > res.Start {
+			export.Type = res.Node.GetImage()
+			offset = res.End
+		}
+
+		node := TypeNode{
+			BaseNode: types.BaseNode{
+				Type: "TYPE",
+			},
+			Pos:  nodes[start].GetPos(),
+			Name: funcType(export),
+		}
+
+		return types.Result{Node: &node, Start: start, End: offset}
+	}
+
+	if nodes[offset].GetType() != "IDENTIFIER" {
+		return types.Result{FailedAt: &nodes[offset]}
+	}
+
+	node := TypeNode{
+		BaseNode: types.BaseNode{
+			Type: "TYPE",
+		},
+		Pos:  nodes[offset].GetPos(),
+		Name: nodes[offset].GetImage(),
+	}
+
+	return types.Result{Node: &node, Start: start, End: offset + 1}
+}
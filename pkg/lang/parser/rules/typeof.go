@@ -0,0 +1,174 @@
+package rules
+
+import (
+	"strings"
+
+	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
+)
+
+// typeOf returns the Go type of an expression if it is known at compile time,
+// or an empty string
+func typeOf(posh *types.PoshFile, node types.Node) string {
+	switch n := node.(type) {
+	case *types.TokenNode:
+		switch n.Type {
+		case "STRING":
+			return "string"
+		case "INTEGER":
+			return "int"
+		case "FLOAT":
+			return "float64"
+		case "BOOLEAN":
+			return "bool"
+		case "IDENTIFIER":
+			valueType, ok := posh.Environment.Get(n.Image)
+			if !ok || valueType == "unknown" || strings.HasPrefix(valueType, "module:") {
+				return ""
+			}
+			return valueType
+		}
+	case *Numeric:
+		return typeOf(posh, n.Value)
+	case *SimpleExpression:
+		return typeOf(posh, n.Value)
+	case *Parens:
+		return typeOf(posh, n.Value)
+	case *Spread:
+		return typeOf(posh, n.Value)
+	case *Unary:
+		return typeOf(posh, n.Value)
+	case *Match:
+		return n.ResultType
+	case *Lambda:
+		if n.Signature.IsFunc {
+			return funcType(n.Signature)
+		}
+	case *RecordLiteral:
+		return n.Identifier.GetImage()
+	case *Field:
+		fieldType, _ := fieldTypeOf(posh.Records[typeOf(posh, n.Value)], n.Name.GetImage())
+		return fieldType
+	case *DotNotation:
+		if n.Enum != "" {
+			return n.Enum
+		}
+
+		if !n.IsValue {
+			break
+		}
+
+		// walk the fields of the records: host.address.port
+		valueType := typeOf(posh, n.Accessors[0])
+		for _, accessor := range n.Accessors[1:] {
+			valueType, _ = fieldTypeOf(posh.Records[valueType], accessor.GetImage())
+		}
+		return valueType
+	case *Template, *Heredoc:
+		return "string"
+	case *Boolean, *ComparisonNode, *ComparisonChain, *Logical, *Negation, *Membership:
+		return "bool"
+	case *ArithmeticNode:
+		// Go does not mix the types of operands
+		if lhs := typeOf(posh, n.Lhs); lhs == typeOf(posh, n.Rhs) {
+			return lhs
+		}
+	case *List:
+		if n.ItemType != "" {
+			return "[]" + n.ItemType
+		}
+	case *Map:
+		if n.KeyType != "" && n.ValueType != "" {
+			return "map[" + n.KeyType + "]" + n.ValueType
+		}
+	case *Index:
+		valueType := typeOf(posh, n.Value)
+		if valueType == "string" {
+			return "byte"
+		} else if _, value := mapTypes(valueType); value != "" {
+			return value
+		}
+		return elemType(valueType)
+	case *Slice:
+		return typeOf(posh, n.Value)
+	case *FunctionCall:
+		// json.Decode(text, Host{}) returns a value of the type of its second
+		// argument
+		if dot, ok := n.Callable.(*DotNotation); ok && !dot.IsValue && len(dot.Accessors) == 2 &&
+			dot.Accessors[0].GetImage() == "json" && dot.Accessors[1].GetImage() == "Decode" && len(n.Args) == 2 {
+			return typeOf(posh, n.Args[1])
+		}
+
+		if dot, ok := n.Callable.(*DotNotation); ok && !dot.IsValue && len(dot.Accessors) == 2 {
+			if result, ok := stdResults[dot.Accessors[0].GetImage()+"."+dot.Accessors[1].GetImage()]; ok {
+				return result
+			}
+		}
+
+		// commands outside of pipes evaluate to their output
+		if n.isStandaloneCommand() {
+			return "string"
+		}
+
+		if n.Method != nil {
+			return n.Method.Result
+		}
+
+		if n.Builtin == nil {
+			return resultType(typeOf(posh, n.Callable))
+		}
+
+		switch n.Callable.GetImage() {
+		case "len":
+			return "int"
+		case "append":
+			if len(n.Args) > 0 {
+				return typeOf(posh, n.Args[0])
+			}
+		case "sorted":
+			if len(n.Args) == 0 {
+				break
+			}
+
+			if key, value := mapTypes(typeOf(posh, n.Args[0])); value != "" {
+				return "iter.Seq2[" + key + ", " + value + "]"
+			}
+		}
+	case *Pipe:
+		if n.IsResult() {
+			return "*exec.Result"
+		}
+		return "string"
+	}
+
+	return ""
+}
+
+// stdResults are the result types of the functions of the standard library
+// that are called through their package: io.Format("%d", n)
+var stdResults = map[string]string{
+	"io.Format":          "string",
+	"json.Encode":        "string",
+	"std.Lines":          "[]string",
+	"strings.ToUpper":    "string",
+	"strings.ToLower":    "string",
+	"strings.TrimSpace":  "string",
+	"strings.TrimPrefix": "string",
+	"strings.TrimSuffix": "string",
+	"strings.Split":      "[]string",
+	"strings.Fields":     "[]string",
+	"strings.Join":       "string",
+	"strings.Contains":   "bool",
+	"strings.HasPrefix":  "bool",
+	"strings.HasSuffix":  "bool",
+	"strings.ReplaceAll": "string",
+	"strings.Repeat":     "string",
+}
+
+// checkType reports the nodes whose type is known and is not expected
+func checkType(posh *types.PoshFile, nodes []types.Node, expected string, what string) {
+	for _, node := range nodes {
+		if nodeType := typeOf(posh, node); nodeType != "" && nodeType != expected {
+			posh.Errorf(node, "cannot use %s as %s %s", nodeType, what, expected)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package types
+
+import "fmt"
+
+// CompileError is a semantic error found during the static analysis
+type CompileError struct {
+	Source  string
+	Pos     *Pos
+	Message string
+}
+
+func (e *CompileError) Error() string {
+	if e.Pos == nil {
+		return fmt.Sprintf("%s: %s", e.Source, e.Message)
+	}
+
+	return fmt.Sprintf("%s:%s: %s", e.Source, e.Pos, e.Message)
+}
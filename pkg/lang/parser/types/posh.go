@@ -1,16 +1,45 @@
 package types
 
-import "go/ast"
+import (
+	"fmt"
+	"go/ast"
+)
 
 type Param struct {
 	Name string
 	Type string
+	// Default is the default value of a parameter of a function, callers
+	// pass it when the argument is left out
+	Default Node
+	// Variadic is set for the last parameter of a function when it takes
+	// the rest of the arguments
+	Variadic bool
 }
 
 type Export struct {
 	Type   string
 	IsFunc bool
 	Params []Param
+	// IsType is set for records and enums, Fields are the fields of records
+	// and Values are the values of enums
+	IsType bool
+	Fields []Param
+	Values []string
+	// Package is the Go package of an imported function, the enum values that
+	// are the defaults of its params are qualified with it
+	Package string
+}
+
+// Loop is a loop around the code that is being analyzed
+type Loop struct {
+	// Label is the label of the loop in Go, a loop that is left from inside of
+	// a match gets one even if it has no label in PoSH
+	Label string
+	// Used is set when a break or a continue needs the label
+	Used bool
+	// Switches counts the match statements between the code and the loop, in
+	// Go a break inside of them leaves the switch and not the loop
+	Switches int
 }
 
 type CompiledFile struct {
@@ -23,17 +52,38 @@ type PoshFile struct {
 	Exports             map[string]Export
 	TopLevelAssignments []ast.Spec
 	CompiledFiles       map[string]CompiledFile
+	Errors              []error
 	StdImports          map[string]bool
 	Source              string
 	BaseDir             string
 	OutputDir           string
 	Package             string
+	// TopLevelTypes are the aliases of the imported records
+	TopLevelTypes []ast.Spec
+	// Records are the records declared in or imported to the file
+	Records map[string]Export
+	// Enums are the enums declared in or imported to the file
+	Enums map[string]Export
+	// Functions are the signatures of the functions declared in or imported
+	// to the file
+	Functions map[string]Export
+	// Loops are the loops around the code that is being analyzed, the
+	// innermost loop is the last one
+	Loops []*Loop
+	// Labels are the labels of the loops of the function that is being
+	// analyzed, Go does not allow using a label twice in a function
+	Labels map[string]bool
 }
 
 func NewPoshFile(source string, basedir string, outputDir string, packageName string, compiledFiles map[string]CompiledFile) *PoshFile {
 	return &PoshFile{
 		Environment:         NewEnvironment(),
 		TopLevelAssignments: []ast.Spec{},
+		TopLevelTypes:       []ast.Spec{},
+		Records:             map[string]Export{},
+		Enums:               map[string]Export{},
+		Functions:           map[string]Export{},
+		Labels:              map[string]bool{},
 		StdImports:          map[string]bool{},
 		Exports:             map[string]Export{},
 		CompiledFiles:       compiledFiles,
@@ -50,3 +100,12 @@ func NewCompiledFile(fileName string) CompiledFile {
 		Exports:  map[string]Export{},
 	}
 }
+
+// Errorf records a compile error at the position of the given node
+func (p *PoshFile) Errorf(node Node, format string, args ...any) {
+	p.Errors = append(p.Errors, &CompileError{
+		Source:  p.Source,
+		Pos:     node.GetPos(),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
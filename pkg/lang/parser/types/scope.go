@@ -32,6 +32,16 @@ func (e *Environment) Set(key, value string) {
 	e.Scopes[e.Cursor][key] = value
 }
 
+// Lookup is like Get, it also returns the index of the scope that has the key
+func (e *Environment) Lookup(key string) (string, int, bool) {
+	for i := e.Cursor; i >= 0; i-- {
+		if val, ok := e.Scopes[i][key]; ok {
+			return val, i, true
+		}
+	}
+	return "", -1, false
+}
+
 func (e *Environment) Get(key string) (string, bool) {
 	// Get a value from the current or any parent scope
 	for i := e.Cursor; i >= 0; i-- {
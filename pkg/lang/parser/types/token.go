@@ -3,12 +3,16 @@ package types
 import (
 	"go/ast"
 	"go/token"
+	"strconv"
 )
 
 type TokenNode struct {
 	BaseNode
 	Image string `json:"image"`
+	// Value is the parsed value of a string, without quotes and escapes
+	Value string `json:"value"`
 	Pos   *Pos   `json:"pos"`
+	End   *Pos   `json:"end"`
 }
 
 func (n *TokenNode) GetPos() *Pos {
@@ -20,12 +24,10 @@ func (n *TokenNode) GetImage() string {
 }
 
 func (n *TokenNode) ToGoAst() ast.Node {
-	if n.Image == "true" {
-		return &ast.Ident{Name: "true"}
-	} else if n.Image == "false" {
-		return &ast.Ident{Name: "false"}
+	if n.Type == "BOOLEAN" {
+		return &ast.Ident{Name: n.Image}
 	} else if n.Type == "STRING" {
-		return &ast.BasicLit{Kind: token.STRING, Value: n.Image}
+		return &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(n.Value)}
 	} else if n.Type == "INTEGER" {
 		return &ast.BasicLit{Kind: token.INT, Value: n.Image}
 	} else if n.Type == "FLOAT" {
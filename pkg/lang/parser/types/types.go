@@ -1,10 +1,19 @@
 package types
 
-import "go/ast"
+import (
+	"fmt"
+	"go/ast"
+)
 
 type Pos struct {
 	Line   int `json:"line"`
 	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// String returns the line and the column of the position, counting from 1
+func (p *Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line+1, p.Column+1)
 }
 
 type Node interface {
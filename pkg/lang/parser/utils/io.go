@@ -11,20 +11,19 @@ import (
 	"github.com/pouya-eghbali/posh/pkg/lang/parser/types"
 )
 
-func Parse(code string, topLevel types.TopLevelMatcher) (types.Node, *types.Node) {
+func Parse(code string, topLevel types.TopLevelMatcher) (types.Node, *types.Node, error) {
 	tokens, err := lexer.Lex(code)
 
 	if err != nil {
-		fmt.Println(err)
-		return &types.BaseNode{}, nil
+		return &types.BaseNode{}, nil, err
 	}
 
 	res := topLevel(tokens, 0)
 	if res.FailedAt != nil {
-		return &types.BaseNode{}, res.FailedAt
+		return &types.BaseNode{}, res.FailedAt, nil
 	}
 
-	return res.Node, nil
+	return res.Node, nil, nil
 }
 
 func ParseFile(path string, topLevel types.TopLevelMatcher) (error, string, types.Node, *types.Node) {
@@ -35,7 +34,11 @@ func ParseFile(path string, topLevel types.TopLevelMatcher) (error, string, type
 	}
 
 	code := string(bytes)
-	parsed, failedAt := Parse(code, topLevel)
+	parsed, failedAt, err := Parse(code, topLevel)
+
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err), code, parsed, nil
+	}
 
 	return nil, code, parsed, failedAt
 }
@@ -72,7 +75,7 @@ func CompilePoshFile(posh *types.PoshFile, topLevel types.TopLevelMatcher) error
 
 	if failedAt != nil {
 		PrintError(code, failedAt)
-		return nil
+		return fmt.Errorf("failed to parse %s", filePath)
 	}
 
 	return parsed.CompileToGo(posh)
@@ -0,0 +1,42 @@
+package std
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type enumFlag[T ~string] struct {
+	value  *T
+	values []T
+}
+
+func (f *enumFlag[T]) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return string(*f.value)
+}
+
+func (f *enumFlag[T]) Set(value string) error {
+	if !slices.Contains(f.values, T(value)) {
+		return fmt.Errorf("must be one of %s", enumValues(f.values))
+	}
+
+	*f.value = T(value)
+	return nil
+}
+
+// EnumFlag is a command line flag that only takes the values of an enum
+func EnumFlag[T ~string](value *T, values ...T) flag.Value {
+	return &enumFlag[T]{value: value, values: values}
+}
+
+func enumValues[T ~string](values []T) string {
+	names := []string{}
+	for _, value := range values {
+		names = append(names, string(value))
+	}
+	return strings.Join(names, ", ")
+}
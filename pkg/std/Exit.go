@@ -0,0 +1,45 @@
+package std
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/pouya-eghbali/posh/pkg/exec"
+)
+
+// Exit terminates the program with the given status code
+func Exit(code int) {
+	os.Exit(code)
+}
+
+// ExitWith terminates the program with the status that corresponds to the
+// value returned from main, which is either an int or an error
+func ExitWith(result any) {
+	switch value := result.(type) {
+	case int:
+		os.Exit(value)
+	case error:
+		fmt.Fprintln(os.Stderr, value)
+		os.Exit(exec.ExitCode(value))
+	default:
+		os.Exit(0)
+	}
+}
+
+// HandleExit is deferred in main. It recovers from unhandled errors, such as
+// a failed command, and terminates the program with the matching status code
+func HandleExit() {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	err, ok := recovered.(error)
+	if _, isRuntime := recovered.(runtime.Error); !ok || isRuntime {
+		panic(recovered)
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(exec.ExitCode(err))
+}
@@ -0,0 +1,14 @@
+package std
+
+import "slices"
+
+// List makes a list of its arguments, it is used for the list literals
+// whose item type is only known to Go
+func List[T any](items ...T) []T {
+	return items
+}
+
+// Contains tells if item is in items: x in xs
+func Contains[T comparable](items []T, item T) bool {
+	return slices.Contains(items, item)
+}
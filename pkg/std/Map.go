@@ -0,0 +1,25 @@
+package std
+
+import (
+	"cmp"
+	"iter"
+	"maps"
+	"slices"
+)
+
+// HasKey tells if key is in m: key in m
+func HasKey[K comparable, V any](m map[K]V, key K) bool {
+	_, ok := m[key]
+	return ok
+}
+
+// Sorted iterates over m in the order of its keys: for k, v in sorted(m)
+func Sorted[K cmp.Ordered, V any](m map[K]V) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			if !yield(key, m[key]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package std
+
+import (
+	"context"
+	"time"
+
+	"github.com/pouya-eghbali/posh/pkg/exec"
+)
+
+// Sleep pauses the program for the given number of seconds, an interrupt
+// ends it early and stops the program
+func Sleep[T int | float64](seconds T) {
+	ctx := exec.Context()
+	timer := time.NewTimer(exec.Seconds(seconds))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		panic(context.Cause(ctx))
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,14 @@
+package std
+
+import "strings"
+
+// Lines splits the output of a command into its lines, the newline at the end
+// of the output does not start a new line
+func Lines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return []string{}
+	}
+
+	return strings.Split(text, "\n")
+}